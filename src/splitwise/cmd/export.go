@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"splitwise/expense"
+	"splitwise/export"
+	"splitwise/model"
+)
+
+// Formats and keywords accepted by EXPORT.
+const (
+	csvFormat    = "CSV"
+	ledgerFormat = "LEDGER"
+	formatFlag   = "--format"
+	currencyFlag = "--currency"
+
+	// stdoutPath makes EXPORT write to standard output instead of a file.
+	stdoutPath = "-"
+)
+
+// Export executes the arguments of an EXPORT command against the current state of the
+// house: CSV <path> [ledger|balances|dues] or LEDGER <path> --format beancount|ledger
+// [--currency <code>].
+func Export(arguments []string) error {
+	if len(arguments) < 2 {
+		return errors.New(expense.InvalidCommandMessage + string(model.EXPORT))
+	}
+	switch arguments[0] {
+	case csvFormat:
+		if len(arguments) > 3 {
+			return errors.New(expense.InvalidCommandMessage + string(model.EXPORT))
+		}
+		kind := export.LEDGER
+		if len(arguments) == 3 {
+			kind = export.CSVKind(arguments[2])
+		}
+		return exportCSV(arguments[1], kind)
+	case ledgerFormat:
+		currency := export.DefaultCurrency
+		if len(arguments) == 6 && arguments[4] == currencyFlag {
+			currency = arguments[5]
+		} else if len(arguments) != 4 {
+			return errors.New(expense.InvalidCommandMessage + string(model.EXPORT))
+		}
+		if arguments[2] != formatFlag || !export.IsValidCurrency(currency) {
+			return errors.New(expense.InvalidCommandMessage + string(model.EXPORT))
+		}
+		format := export.LedgerFormat(arguments[3])
+		return writeOutput(arguments[1], func(w io.Writer) error {
+			return export.WriteLedger(w, globalStorage.GetHistory(), format, currency)
+		})
+	default:
+		return errors.New(expense.InvalidCommandMessage + string(model.EXPORT))
+	}
+}
+
+// handleExport executes EXPORT and returns the result to print.
+func handleExport(arguments []string) string {
+	if err := Export(arguments); err != nil {
+		return err.Error()
+	}
+	return string(model.SUCCESS)
+}
+
+// exportCSV writes the ledger, balances or dues of the house as CSV to path.
+func exportCSV(path string, kind export.CSVKind) error {
+	var write func(w io.Writer) error
+	switch kind {
+	case export.LEDGER:
+		write = func(w io.Writer) error { return export.WriteLedgerCSV(w, globalStorage.GetHistory()) }
+	case export.BALANCES:
+		write = func(w io.Writer) error { return export.WriteBalancesCSV(w, globalStorage.GetNetBalances()) }
+	case export.DUES:
+		write = func(w io.Writer) error { return export.WriteDuesCSV(w, globalStorage.GetTransactions()) }
+	default:
+		return errors.New(expense.InvalidCommandMessage + string(model.EXPORT))
+	}
+	return writeOutput(path, write)
+}
+
+// writeOutput runs write against the file at path, or standard output for "-".
+func writeOutput(path string, write func(w io.Writer) error) error {
+	if path == stdoutPath {
+		return write(os.Stdout)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"strings"
+
+	"splitwise/expense"
+	"splitwise/export"
+	"splitwise/model"
+)
+
+// Dues drawn by GRAPH.
+const (
+	rawGraph        = "raw"
+	simplifiedGraph = "simplified"
+)
+
+// handleGraph executes GRAPH [raw|simplified] --format dot|mermaid and returns the graph
+// to print. The simplified dues are drawn unless raw is asked for.
+func handleGraph(arguments []string) string {
+	kind := simplifiedGraph
+	if len(arguments) == 3 {
+		kind, arguments = arguments[0], arguments[1:]
+	}
+	if len(arguments) != 2 || arguments[0] != formatFlag || (kind != rawGraph && kind != simplifiedGraph) {
+		return expense.InvalidCommandMessage + string(model.GRAPH)
+	}
+
+	dues := globalStorage.GetTransactions()
+	if kind == rawGraph {
+		dues = debtorDues(globalStorage.GetRawDues())
+	}
+	var out strings.Builder
+	if err := export.WriteGraph(&out, dues, globalStorage.GetNetBalances(), export.GraphFormat(arguments[1])); err != nil {
+		return expense.InvalidCommandMessage + string(model.GRAPH)
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// debtorDues turns raw dues keyed creditor -> debtor around, keying them debtor -> creditor.
+func debtorDues(dues map[string]map[string]int64) map[string]map[string]int64 {
+	turned := make(map[string]map[string]int64)
+	for creditor, row := range dues {
+		for debtor, amount := range row {
+			if turned[debtor] == nil {
+				turned[debtor] = make(map[string]int64)
+			}
+			turned[debtor][creditor] = amount
+		}
+	}
+	return turned
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"splitwise/expense"
+	"splitwise/importer"
+	"splitwise/model"
+)
+
+// Keywords of IMPORT.
+const (
+	splitwiseFormat = "SPLITWISE"
+	mapFlag         = "--map"
+	reviewFlag      = "--review"
+)
+
+// handleImport executes IMPORT in one of its formats and returns the lines to print.
+func handleImport(arguments []string) []string {
+	var result []string
+	if len(arguments) > 0 && arguments[0] == splitwiseFormat {
+		result = importSplitwise(arguments)
+	} else {
+		result = importStatement(arguments)
+	}
+	if result[len(result)-1] == string(model.SUCCESS) && commandJournal != nil {
+		result[len(result)-1] = checkpoint()
+	}
+	return result
+}
+
+// importStatement executes IMPORT CSV <path> --map <mapping> [--review]. It prints one line
+// per statement row: "ROW <n> IMPORTED <amount> <members...>" for each expense added,
+// DUPLICATE or SKIPPED for rows left out, or the error that stopped the row. With --review
+// nothing is committed, proposed rows show as PROPOSED and the last line is DRY_RUN.
+func importStatement(arguments []string) []string {
+	review := len(arguments) == 5 && arguments[4] == reviewFlag
+	if (len(arguments) != 4 && !review) || arguments[0] != csvFormat || arguments[2] != mapFlag {
+		return []string{expense.InvalidCommandMessage + string(model.IMPORT)}
+	}
+	mapping, err := importer.LoadMapping(arguments[3])
+	if err != nil {
+		return []string{err.Error()}
+	}
+	file, err := os.Open(arguments[1])
+	if err != nil {
+		return []string{err.Error()}
+	}
+	defer file.Close()
+	proposals, err := importer.ReadStatement(file, mapping, globalStorage.IsImported)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	result := make([]string, 0, len(proposals)+1)
+	for _, proposal := range proposals {
+		status := string(proposal.Status)
+		if proposal.Status == importer.PROPOSED && !review {
+			status = commitProposal(proposal)
+		}
+		line := fmt.Sprintf("ROW %d %s", proposal.Row, status)
+		if proposal.Status == importer.PROPOSED && (review || status == string(importer.IMPORTED)) {
+			line += " " + proposal.Spend()
+		}
+		result = append(result, line+" # "+proposal.Note())
+	}
+	if review {
+		return append(result, string(model.DRY_RUN))
+	}
+	return append(result, string(model.SUCCESS))
+}
+
+// commitProposal adds the expense proposed for a statement row, dated like the row and in
+// the category of its rule, and remembers the row so it is never imported again. It returns
+// IMPORTED or the error.
+func commitProposal(proposal importer.Proposal) string {
+	today := globalStorage.GetToday()
+	globalStorage.SetToday(proposal.Date)
+	defer globalStorage.SetToday(today)
+
+	var err error
+	if proposal.Split != "" {
+		_, err = trackerService.AddWeightedExpense(proposal.Amount, proposal.Payer, proposal.Split)
+	} else {
+		_, err = trackerService.AddExpense(float64(proposal.Amount), proposal.Members())
+	}
+	if err != nil {
+		return err.Error()
+	}
+	globalStorage.CategorizeLastTransaction(proposal.Category)
+	globalStorage.RecordImport(proposal.Fingerprint)
+	return string(importer.IMPORTED)
+}
+
+// importSplitwise executes IMPORT SPLITWISE <path>, replaying a Splitwise group export.
+// Members missing from the house move in first. Once every row is posted, the balances the
+// import added must match the export's total balances. Any failure restores the house as it
+// was. It prints "MOVE_IN <member>" per member added, "IMPORTED <rows>" and SUCCESS.
+func importSplitwise(arguments []string) []string {
+	if len(arguments) != 2 {
+		return []string{expense.InvalidCommandMessage + string(model.IMPORT)}
+	}
+	file, err := os.Open(arguments[1])
+	if err != nil {
+		return []string{err.Error()}
+	}
+	defer file.Close()
+	export, err := importer.ReadSplitwise(file)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	var missing []string
+	for _, member := range export.Members {
+		if !globalStorage.CheckHousemateExists(member) {
+			missing = append(missing, member)
+		}
+	}
+	if globalStorage.GetNumberOfHousemates()+len(missing) > model.MAX_HOUSEMATES {
+		return []string{string(model.HOUSEFUL)}
+	}
+
+	backup := globalStorage.Snapshot()
+	result, err := replaySplitwise(export, missing)
+	if err != nil {
+		globalStorage.Restore(backup)
+		return []string{err.Error()}
+	}
+	return append(result, fmt.Sprintf("IMPORTED %d", len(export.Rows)), string(model.SUCCESS))
+}
+
+// replaySplitwise moves in the missing members, posts every row of the export dated like
+// the row, and checks the resulting balances against the export's totals.
+func replaySplitwise(export importer.SplitwiseExport, missing []string) ([]string, error) {
+	var result []string
+	for _, member := range missing {
+		if _, err := housemateService.MoveIn(member); err != nil {
+			return nil, err
+		}
+		result = append(result, string(model.MOVE_IN)+" "+member)
+	}
+
+	before := globalStorage.GetNetBalances()
+	today := globalStorage.GetToday()
+	defer globalStorage.SetToday(today)
+	for _, row := range export.Rows {
+		if len(row.Effects) == 0 {
+			continue
+		}
+		globalStorage.SetToday(row.Date)
+		if _, err := trackerService.ImportTransaction(row.Transaction()); err != nil {
+			return nil, fmt.Errorf("ROW %d %w", row.Row, err)
+		}
+	}
+
+	after := globalStorage.GetNetBalances()
+	for _, member := range export.Members {
+		if got := after[member] - before[member]; got != export.Totals[member] {
+			return nil, fmt.Errorf("%s %s %d %d", model.BALANCE_MISMATCH, member, export.Totals[member], got)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const splitwiseExport = `Date,Description,Category,Cost,Currency,Alice Smith,Bob Jones,Carol
+
+2026-01-02,Groceries,Groceries,30.00,USD,20.00,-10.00,-10.00
+2026-01-03,Pizza,Dining out,10.00,USD,-3.33,6.67,-3.34
+2026-01-04,Internet,Utilities,50.00,USD,-16.67,-16.67,33.34
+2026-01-05,Bob Jones paid Alice Smith,Payment,5.00,USD,-5.00,5.00,0.00
+
+2026-01-06,Total balance, , ,USD,-5.00,-15.00,20.00
+`
+
+func TestImportSplitwise(t *testing.T) {
+	dir := t.TempDir()
+	exports := map[string]string{
+		"export.csv":    splitwiseExport,
+		"mismatch.csv":  strings.Replace(splitwiseExport, "-5.00,-15.00,20.00", "-6.00,-14.00,20.00", 1),
+		"backdated.csv": strings.Replace(splitwiseExport, "2026-01-05,Bob", "2025-12-31,Bob", 1),
+	}
+	for name, content := range exports {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		setup    []string
+		command  string
+		output   string
+		members  int
+		history  int
+		balances map[string]int64
+	}{
+		// TEST CASE 1: Missing members move in and the balances match the export
+		{
+			name:     "auto move in",
+			setup:    []string{"MOVE_IN ALICE_SMITH", "SET CHECK ON"},
+			command:  "IMPORT SPLITWISE " + filepath.Join(dir, "export.csv"),
+			output:   "MOVE_IN BOB_JONES\nMOVE_IN CAROL\nIMPORTED 4\nSUCCESS",
+			members:  3,
+			history:  4,
+			balances: map[string]int64{"ALICE_SMITH": -5, "BOB_JONES": -15, "CAROL": 20},
+		},
+		// TEST CASE 2: An export whose rows do not add up to its totals changes nothing
+		{
+			name:     "mismatching totals",
+			setup:    []string{"MOVE_IN ALICE_SMITH", "MOVE_IN BOB_JONES", "SPEND 100 ALICE_SMITH BOB_JONES"},
+			command:  "IMPORT SPLITWISE " + filepath.Join(dir, "mismatch.csv"),
+			output:   "the rows do not add up to the total balance of ALICE_SMITH",
+			members:  2,
+			history:  1,
+			balances: map[string]int64{"ALICE_SMITH": 50, "BOB_JONES": -50},
+		},
+		// TEST CASE 3: A row that cannot be posted restores the house, moves included
+		{
+			name:     "rollback",
+			setup:    []string{"MOVE_IN ALICE_SMITH", "DATE 2026-01-01", "CLOSE_PERIOD 2025-12"},
+			command:  "IMPORT SPLITWISE " + filepath.Join(dir, "backdated.csv"),
+			output:   "ROW 5 PERIOD_CLOSED",
+			members:  1,
+			history:  0,
+			balances: map[string]int64{"ALICE_SMITH": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		globalStorage.Reset()
+		t.Run(tt.name, func(t *testing.T) {
+			for _, line := range tt.setup {
+				runLine(t, line)
+			}
+			if output := runLine(t, tt.command); output != tt.output {
+				t.Errorf("Expected %q, got %q", tt.output, output)
+			}
+			if got := globalStorage.GetNumberOfHousemates(); got != tt.members {
+				t.Errorf("Expected %d housemates, got %d", tt.members, got)
+			}
+			if got := len(globalStorage.GetHistory()); got != tt.history {
+				t.Errorf("Expected %d transactions, got %d", tt.history, got)
+			}
+			accounts := globalStorage.GetAccounts()
+			for member, balance := range tt.balances {
+				if accounts[member] != balance {
+					t.Errorf("Expected %s to have %d, got %d", member, balance, accounts[member])
+				}
+			}
+			if violations := globalStorage.CheckInvariants(); len(violations) != 0 {
+				t.Errorf("Expected consistent books, got %v", violations)
+			}
+		})
+	}
+}
+
+// runLine processes one line of input and returns what it printed, without the final newline
+func runLine(t *testing.T, line string) string {
+	t.Helper()
+	return captureOutput(t, func() error {
+		_, err := processLine(line, nil)
+		return err
+	})
+}
+
+// captureOutput runs f and returns what it printed, without the final newline
+func captureOutput(t *testing.T, f func() error) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	err = f()
+	os.Stdout = stdout
+	writer.Close()
+	output, _ := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSuffix(string(output), "\n")
+}
+
+func TestImportStatement(t *testing.T) {
+	dir := t.TempDir()
+	statement := filepath.Join(dir, "statement.csv")
+	mapping := filepath.Join(dir, "mapping.json")
+	os.WriteFile(statement, []byte("Date,Details,Amount\n2026-01-03,GROCER,60\n2026-01-04,HARDWARE,20\n2026-01-05,CAFE,9\n"), 0o644)
+	os.WriteFile(mapping, []byte(`{"date": "Date", "amount": "Amount", "description": "Details",
+		"payer": "ANDY", "beneficiaries": ["ANDY", "WOODY"],
+		"rules": [{"match": "grocer", "category": "Groceries"}, {"match": "cafe", "beneficiaries": ["REX"]}]}`), 0o644)
+
+	globalStorage.Reset()
+	defer globalStorage.Reset()
+	runLine(t, "MOVE_IN ANDY")
+	runLine(t, "MOVE_IN WOODY")
+	output := runLine(t, "IMPORT CSV "+statement+" --map "+mapping)
+	if !strings.HasSuffix(output, "SUCCESS") {
+		t.Fatalf("Expected SUCCESS, got %q", output)
+	}
+
+	// TEST CASE 1: The category of the matching rule is kept with the expense
+	history := globalStorage.GetHistory()
+	if len(history) != 2 || history[0].Category != "Groceries" || history[1].Category != "" {
+		t.Errorf("Expected the categories Groceries and none, got %+v", history)
+	}
+
+	// TEST CASE 2: A row sharing with someone who does not live in the house is refused
+	if !strings.Contains(output, "ROW 4 MEMBER_NOT_FOUND # 2026-01-05 CAFE\n") {
+		t.Errorf("Expected row 4 to be refused, got %q", output)
+	}
+}
@@ -2,24 +2,112 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 
 	"splitwise/expense"
 	"splitwise/global"
+	"splitwise/journal"
 	"splitwise/model"
+	"splitwise/state"
 )
 
-var terminalCmd *expense.TerminalCmd
+var (
+	globalStorage    *global.GlobalMapStorage
+	housemateService expense.HousemateService
+	trackerService   expense.TrackerService
+	terminalCmd      *expense.TerminalCmd
+	commandJournal   *journal.Journal
+)
+
+// dryRunFlag makes MIGRATE report the migrations without writing the file.
+const dryRunFlag = "--dry-run"
+
+// blockCommands maps each command that opens a multi-line block to the command closing it.
+var blockCommands = map[model.CommandType]model.CommandType{
+	model.BILL: model.END_BILL,
+}
 
 func init() {
-	globalStorage := global.NewGlobalMapStorage()
-	housemateService := expense.NewHousemateServiceImpl(globalStorage)
-	trackerService := expense.NewTrackerServiceImpl(globalStorage)
+	globalStorage = global.NewGlobalMapStorage()
+	housemateService = expense.NewHousemateServiceImpl(globalStorage)
+	trackerService = expense.NewTrackerServiceImpl(globalStorage)
 	terminalCmd = expense.NewTerminalCmd(housemateService, trackerService)
 }
 
+// OpenJournal makes every mutating command durable in the write-ahead journal kept in dir.
+// The latest snapshot is loaded and the commands journaled after it are replayed first. A
+// record that cannot be replayed is reported and skipped, so it never locks the house.
+func OpenJournal(dir string) error {
+	opened, err := journal.Open(dir, journal.DefaultSnapshotInterval)
+	if err != nil {
+		return err
+	}
+	data, records, err := opened.Load()
+	if err != nil {
+		opened.Close()
+		return err
+	}
+	if data != nil {
+		snapshot, err := state.Decode(data)
+		if err != nil {
+			opened.Close()
+			return fmt.Errorf("error decoding the snapshot: %w", err)
+		}
+		globalStorage.Restore(snapshot)
+	}
+	for i, record := range records {
+		if err := replayRecord(record); err != nil {
+			fmt.Printf("Skipped journal record %d: %v\n", i+1, err)
+		}
+	}
+	commandJournal = opened
+	return nil
+}
+
+// replayRecord executes a journaled command, turning a panic into an error.
+func replayRecord(record []byte) (err error) {
+	var command model.Command
+	if err := json.Unmarshal(record, &command); err != nil {
+		return fmt.Errorf("error decoding a journal record: %w", err)
+	}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("error replaying %s: %v", command.CommandType, recovered)
+		}
+	}()
+	terminalCmd.ExecuteCommand(command)
+	return nil
+}
+
+// CloseJournal closes the journal opened by OpenJournal, if any.
+func CloseJournal() error {
+	if commandJournal == nil {
+		return nil
+	}
+	err := commandJournal.Close()
+	commandJournal = nil
+	return err
+}
+
+// LoadState replaces the state of the house with the state file at path.
+func LoadState(path string) error {
+	snapshot, err := state.Load(path)
+	if err != nil {
+		return err
+	}
+	globalStorage.Restore(snapshot)
+	return nil
+}
+
+// SaveState writes the state of the house to the state file at path.
+func SaveState(path string) error {
+	return state.Save(path, globalStorage.Snapshot())
+}
+
 // ProcessFile reads commands from the specified file and processes each line.
 func ProcessFile(filePath string) error {
 	file, err := os.Open(filePath)
@@ -28,29 +116,188 @@ func ProcessFile(filePath string) error {
 	}
 	defer file.Close()
 	scanner := bufio.NewScanner(file)
+	var block *model.Command
 	for scanner.Scan() {
-		if err := processLine(scanner.Text()); err != nil {
+		block, err = processLine(scanner.Text(), block)
+		if err != nil {
 			return fmt.Errorf("error processing line: %w", err)
 		}
 	}
 	if err := scanner.Err(); err != nil {
 		return fmt.Errorf("error reading the input file: %w", err)
 	}
+	if block != nil {
+		return fmt.Errorf("error processing line: %w", errors.New("missing "+string(blockCommands[block.CommandType])))
+	}
 	return nil
 }
 
 // processLine parses and executes a command from a line of input.
-func processLine(line string) error {
+// Lines inside a multi-line block are collected into the open block, which is
+// returned until its closing line executes it.
+func processLine(line string, block *model.Command) (*model.Command, error) {
 	args := strings.Fields(line)
 	if len(args) == 0 {
-		return nil
+		return block, nil
 	}
 	commandType := model.CommandType(args[0])
+	if block != nil {
+		if commandType != blockCommands[block.CommandType] {
+			block.Body = append(block.Body, args)
+			return block, nil
+		}
+		return nil, executeCommand(*block)
+	}
 	commandModel := model.Command{
 		CommandType: commandType,
 		Arguments:   args[1:],
 	}
-	result := terminalCmd.ExecuteCommand(commandModel)
+	if _, ok := blockCommands[commandType]; ok {
+		return &commandModel, nil
+	}
+	return nil, executeCommand(commandModel)
+}
+
+// executeCommand journals a mutating command before executing it and prints the result.
+// A snapshot is taken when the journal asks for one, on CHECKPOINT and after LOAD and
+// IMPORT, which are never journaled since their files may change before a replay. The books
+// are checked after every mutating command, LOAD and IMPORT included.
+func executeCommand(command model.Command) error {
+	switch command.CommandType {
+	case model.CHECKPOINT:
+		fmt.Println(checkpoint())
+		return nil
+	case model.SAVE:
+		fmt.Println(handleStateFile(command))
+		return nil
+	case model.LOAD:
+		fmt.Println(handleStateFile(command))
+		reportInvariants()
+		return nil
+	case model.EXPORT:
+		fmt.Println(handleExport(command.Arguments))
+		return nil
+	case model.GRAPH:
+		fmt.Println(handleGraph(command.Arguments))
+		return nil
+	case model.STATEMENT:
+		fmt.Println(handleStatement(command.Arguments))
+		return nil
+	case model.IMPORT:
+		fmt.Println(strings.Join(handleImport(command.Arguments), "\n"))
+		reportInvariants()
+		return nil
+	case model.MIGRATE:
+		fmt.Println(strings.Join(handleMigrate(command.Arguments), "\n"))
+		return nil
+	}
+	if commandJournal != nil && command.CommandType.IsMutating() {
+		payload, err := json.Marshal(command)
+		if err != nil {
+			return err
+		}
+		if err := commandJournal.Append(payload); err != nil {
+			return err
+		}
+	}
+	result := terminalCmd.ExecuteCommand(command)
 	fmt.Println(result)
+	if command.CommandType.IsMutating() {
+		reportInvariants()
+	}
+	if commandJournal != nil && commandJournal.NeedsCheckpoint() {
+		if result := checkpoint(); result != string(model.SUCCESS) {
+			return errors.New(result)
+		}
+	}
 	return nil
 }
+
+// reportInvariants prints the violations found by checkInvariants, if any.
+func reportInvariants() {
+	if violations := checkInvariants(); len(violations) > 0 {
+		fmt.Println(strings.Join(violations, "\n"))
+	}
+}
+
+// checkInvariants checks the books when the CHECK setting is ON and returns FAILURE
+// followed by the violations found, or nothing when the books are consistent.
+func checkInvariants() []string {
+	if globalStorage.GetSetting(model.CHECK_SETTING) != model.CHECK_ON {
+		return nil
+	}
+	violations := trackerService.Check()
+	if len(violations) == 0 {
+		return nil
+	}
+	return append([]string{string(model.FAILURE)}, violations...)
+}
+
+// handleStateFile executes SAVE or LOAD and returns the result to print.
+func handleStateFile(command model.Command) string {
+	if len(command.Arguments) != 1 {
+		return expense.InvalidCommandMessage + string(command.CommandType)
+	}
+	if command.CommandType == model.SAVE {
+		if err := SaveState(command.Arguments[0]); err != nil {
+			return err.Error()
+		}
+		return string(model.SUCCESS)
+	}
+	if err := LoadState(command.Arguments[0]); err != nil {
+		return err.Error()
+	}
+	if commandJournal != nil {
+		return checkpoint()
+	}
+	return string(model.SUCCESS)
+}
+
+// handleMigrate executes MIGRATE <path> [--dry-run], upgrading a state file to the current
+// schema version. It returns one line per migration followed by SUCCESS, or by DRY_RUN when
+// the file was left untouched.
+func handleMigrate(arguments []string) []string {
+	if len(arguments) == 0 || len(arguments) > 2 || (len(arguments) == 2 && arguments[1] != dryRunFlag) {
+		return []string{expense.InvalidCommandMessage + string(model.MIGRATE)}
+	}
+	data, err := os.ReadFile(arguments[0])
+	if err != nil {
+		return []string{err.Error()}
+	}
+	upgraded, applied, err := state.Migrate(data)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	snapshot, err := state.Decode(upgraded)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	result := make([]string, 0, len(applied)+1)
+	for _, step := range applied {
+		result = append(result, string(model.MIGRATE)+" "+step)
+	}
+	if len(arguments) == 2 {
+		return append(result, string(model.DRY_RUN))
+	}
+	if len(applied) > 0 {
+		if err := state.Save(arguments[0], snapshot); err != nil {
+			return append(result, err.Error())
+		}
+	}
+	return append(result, string(model.SUCCESS))
+}
+
+// checkpoint writes a snapshot of the current state to the journal.
+func checkpoint() string {
+	if commandJournal == nil {
+		return string(model.NO_JOURNAL)
+	}
+	data, err := state.Encode(globalStorage.Snapshot())
+	if err != nil {
+		return err.Error()
+	}
+	if err := commandJournal.Checkpoint(data); err != nil {
+		return err.Error()
+	}
+	return string(model.SUCCESS)
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"splitwise/journal"
+)
+
+func TestOpenJournalSkipsBadRecords(t *testing.T) {
+	dir := t.TempDir()
+	globalStorage.Reset()
+	defer globalStorage.Reset()
+
+	// TEST CASE 1: Malformed commands are refused without panicking
+	if output := captureOutput(t, func() error { return OpenJournal(dir) }); output != "" {
+		t.Errorf("Expected nothing printed, got %q", output)
+	}
+	for _, tc := range []struct {
+		line   string
+		output string
+	}{
+		{"MOVE_IN A", "SUCCESS"},
+		{"MOVE_IN B", "SUCCESS"},
+		{"CLEAR_DUE A B", "Invalid command: CLEAR_DUE"},
+		{"SPEND", "Invalid command: SPEND"},
+		{"DUES", "Invalid command: DUES"},
+	} {
+		if output := runLine(t, tc.line); output != tc.output {
+			t.Errorf("%s: expected %q, got %q", tc.line, tc.output, output)
+		}
+	}
+	CloseJournal()
+
+	// A record that cannot be decoded, as if written by another version
+	opened, err := journal.Open(dir, journal.DefaultSnapshotInterval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened.Load()
+	opened.Append([]byte("not a command"))
+	opened.Close()
+
+	// TEST CASE 2: Reopening the journal replays the good records and skips the bad one
+	globalStorage.Reset()
+	output := captureOutput(t, func() error { return OpenJournal(dir) })
+	defer CloseJournal()
+	if expected := "Skipped journal record 5: error decoding a journal record"; !strings.HasPrefix(output, expected) {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+	if names := globalStorage.GetHousemateNames(); len(names) != 2 {
+		t.Errorf("Expected A and B to live in the house, got %v", names)
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"splitwise/expense"
+	"splitwise/export"
+	"splitwise/model"
+)
+
+// handleStatement executes STATEMENT <member> <yyyy-mm> [--format txt|md|html] and returns
+// the statement to print. Statements of former housemates can still be printed.
+func handleStatement(arguments []string) string {
+	format := export.TEXT_STATEMENT
+	switch {
+	case len(arguments) == 2:
+	case len(arguments) == 4 && arguments[2] == formatFlag:
+		format = export.StatementFormat(arguments[3])
+	default:
+		return expense.InvalidCommandMessage + string(model.STATEMENT)
+	}
+	member := arguments[0]
+	if !globalStorage.CheckHousemateExists(member) && !isFormerHousemate(member) {
+		return string(model.MEMBER_NOT_FOUND)
+	}
+	month, err := time.Parse(model.MonthLayout, arguments[1])
+	if err != nil {
+		return expense.InvalidDateMessage + arguments[1]
+	}
+
+	statement := export.BuildStatement(globalStorage.GetHistory(), member, month)
+	var out strings.Builder
+	if err := export.WriteStatement(&out, statement, format); err != nil {
+		return expense.InvalidCommandMessage + string(model.STATEMENT)
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// isFormerHousemate reports whether the member has moved out.
+func isFormerHousemate(member string) bool {
+	for _, former := range globalStorage.GetFormerHousemateNames() {
+		if former == member {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package expense
+
+import (
+	"errors"
+	"sort"
+	"splitwise/model"
+)
+
+// AddBill adds an itemized expense paid by a single housemate. Every item is split evenly
+// among its participants, and tax, tip and discount lines are apportioned in proportion to
+// what each housemate's items cost. The bill is recorded as one expense with its breakdown.
+func (t *TrackerServiceImpl) AddBill(bill model.Bill) (string, error) {
+	lines := make([]model.BillLine, len(bill.Lines))
+	subtotals := make(map[string]int64)
+	var itemsTotal, discountTotal int64
+	for i, line := range bill.Lines {
+		if line.Amount < 0 {
+			return "", errors.New(string(model.INVALID_BILL))
+		}
+		switch line.Kind {
+		case model.ITEM_LINE:
+			if len(line.Participants) == 0 {
+				return "", errors.New(string(model.INVALID_BILL))
+			}
+			line.Shares = splitAmongBeneficiaries(line.Amount, line.Participants)
+			for participant, share := range line.Shares {
+				subtotals[participant] += share
+			}
+			itemsTotal += line.Amount
+		case model.DISCOUNT_LINE, model.TAX_LINE, model.TIP_LINE:
+			if len(line.Participants) != 0 {
+				return "", errors.New(string(model.INVALID_BILL))
+			}
+			if line.Kind == model.DISCOUNT_LINE {
+				discountTotal += line.Amount
+			}
+		default:
+			return "", errors.New(string(model.INVALID_BILL))
+		}
+		lines[i] = line
+	}
+	if itemsTotal == 0 || discountTotal >= itemsTotal {
+		return "", errors.New(string(model.INVALID_BILL))
+	}
+
+	shares := make(map[string]int64)
+	var total int64
+	for i, line := range lines {
+		sign := int64(1)
+		if line.Kind == model.DISCOUNT_LINE {
+			sign = -1
+		}
+		if line.Kind != model.ITEM_LINE {
+			line.Shares = apportion(line.Amount, subtotals, itemsTotal)
+			lines[i] = line
+		}
+		for participant, share := range line.Shares {
+			shares[participant] += sign * share
+		}
+		total += sign * line.Amount
+	}
+
+	return t.postTransaction(model.Transaction{
+		Type:   model.BILL_TRANSACTION,
+		Amount: total,
+		Payers: map[string]int64{bill.Payer: total},
+		Shares: shares,
+		Items:  lines,
+	})
+}
+
+// apportion divides an amount in proportion to the weights, which add up to total.
+// Units lost to rounding go to the largest remainders, ties broken by name.
+func apportion(amount int64, weights map[string]int64, total int64) map[string]int64 {
+	names := make([]string, 0, len(weights))
+	for name := range weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	shares := make(map[string]int64, len(names))
+	remainders := make(map[string]int64, len(names))
+	leftover := amount
+	for _, name := range names {
+		shares[name] = amount * weights[name] / total
+		remainders[name] = amount * weights[name] % total
+		leftover -= shares[name]
+	}
+
+	sort.SliceStable(names, func(i, j int) bool {
+		return remainders[names[i]] > remainders[names[j]]
+	})
+	for i := int64(0); i < leftover; i++ {
+		shares[names[i]]++
+	}
+	return shares
+}
@@ -0,0 +1,88 @@
+package expense
+
+import (
+	"errors"
+	"fmt"
+	"splitwise/model"
+	"time"
+)
+
+// ClosePeriod locks a month, and every month before it, so that no transaction can be
+// dated in them, and keeps the net balance of every member at its end as the closing
+// position. It returns the closing
+// position as "<member> <balance>" lines sorted by name.
+func (t *TrackerServiceImpl) ClosePeriod(month time.Time) ([]string, error) {
+	key := month.Format(model.MonthLayout)
+	if _, ok := t.storage.GetClosedPeriod(key); ok {
+		return nil, errors.New(string(model.PERIOD_CLOSED))
+	}
+
+	balances := t.balancesBefore(month.AddDate(0, 1, 0))
+	t.storage.ClosePeriod(model.ClosedPeriod{Month: key, Balances: balances})
+	t.storage.RecordAudit(model.AuditRecord{Date: t.storage.GetToday(), Action: model.CLOSE_PERIOD, Month: key})
+
+	result := make([]string, 0, len(balances))
+	for _, name := range sortedNames(balances) {
+		result = append(result, fmt.Sprintf("%s %d", name, balances[name]))
+	}
+	return result, nil
+}
+
+// ReopenPeriod unlocks a closed month and writes the reason to the audit log. Every month
+// up to the latest closed one stays locked, so only the latest closed month can be reopened.
+func (t *TrackerServiceImpl) ReopenPeriod(month time.Time, reason string) (string, error) {
+	key := month.Format(model.MonthLayout)
+	if _, ok := t.storage.GetClosedPeriod(key); !ok {
+		return "", errors.New(string(model.PERIOD_NOT_CLOSED))
+	}
+	if key != t.storage.GetLatestClosedMonth() {
+		return "", errors.New(string(model.PERIOD_CLOSED))
+	}
+	t.storage.ReopenPeriod(key)
+	t.storage.RecordAudit(model.AuditRecord{Date: t.storage.GetToday(), Action: model.REOPEN_PERIOD, Month: key, Reason: reason})
+	return string(model.SUCCESS), nil
+}
+
+// ShowAudit returns the audit log, oldest first, as "<date> <action> <month> [<reason>]".
+// Records made before a house date was set show "-" as their date.
+func (t *TrackerServiceImpl) ShowAudit() []string {
+	audit := t.storage.GetAudit()
+	result := make([]string, 0, len(audit))
+	for _, record := range audit {
+		date := "-"
+		if !record.Date.IsZero() {
+			date = record.Date.Format(model.DateLayout)
+		}
+		line := fmt.Sprintf("%s %s %s", date, record.Action, record.Month)
+		if record.Reason != "" {
+			line += " " + record.Reason
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+// balancesBefore returns the net balance of every current housemate, and of anyone else
+// with a balance, from the transactions dated before end. Undated transactions take the
+// date of the transaction before them, and come before any date if there is none.
+func (t *TrackerServiceImpl) balancesBefore(end time.Time) map[string]int64 {
+	balances := make(map[string]int64)
+	for _, name := range t.storage.GetHousemateNames() {
+		balances[name] = model.ZERO_DUE
+	}
+	var date time.Time
+	for _, transaction := range t.storage.GetHistory() {
+		if !transaction.Date.IsZero() {
+			date = transaction.Date
+		}
+		if !transaction.Type.AffectsDues() || !date.Before(end) {
+			continue
+		}
+		for member, effect := range transaction.Effects() {
+			if effect != model.ZERO_DUE {
+				balances[member] += effect
+			}
+		}
+	}
+	return balances
+}
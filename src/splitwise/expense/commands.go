@@ -1,8 +1,12 @@
 package expense
 
 import (
+	"errors"
+	"math"
 	"splitwise/model"
 	"strconv"
+	"strings"
+	"time"
 )
 
 const (
@@ -12,12 +16,33 @@ const (
 
 	InvalidAmountMessage  = "Invalid amount: "
 	InvalidCommandMessage = "Invalid command: "
+	InvalidDateMessage    = "Invalid date: "
+
+	PaidKeyword     = "PAID"
+	SettleKeyword   = "SETTLE"
+	TransferKeyword = "TRANSFER_TO"
+	DepositKeyword  = "DEPOSIT"
+	ToKeyword       = "TO"
+	UsingKeyword    = "USING"
+	ForKeyword      = "FOR"
+	AmountSeparator = ":"
+
+	FormatFlag = "--format"
+	TextFormat = "text"
+	JSONFormat = "json"
 )
 
 // HousemateService defines the contract for housemate operations.
 type HousemateService interface {
 	MoveIn(housemate string) (string, error)
 	MoveOut(housemate string) (string, error)
+	ConfigureSetting(setting, value string) (string, error)
+	ShowFormer() []string
+	MoveInWithDeposit(housemate, holder string, amount int64) (string, error)
+	ShowDeposits() []string
+	SetDate(date time.Time) (string, error)
+	MarkAway(housemate string, from, to time.Time) (string, error)
+	CheckSettleable(housemate string) error
 }
 
 // TrackerService defines the contract for expense tracking operations.
@@ -25,6 +50,29 @@ type TrackerService interface {
 	AddExpense(amount float64, beneficiaries []string) (string, error)
 	ShowDues(housemate string) ([]string, error)
 	ClearDues(from, to string, amount int64) (string, error)
+	AddMultiPayerExpense(amount int64, payers map[string]int64, beneficiaries []string) (string, error)
+	Lend(amount int64, lender, borrower string) (string, error)
+	PayFor(amount int64, payer string, beneficiaries []string) (string, error)
+	Refund(amount int64, receiver string, beneficiaries []string) (string, error)
+	RefundExpense(id int) (string, error)
+	ShowHistory() []string
+	Pay(member string, amount int64, strategy model.AllocationStrategy) ([]string, error)
+	SettleMember(member string) ([]string, error)
+	TransferDues(member, receiver string) ([]string, error)
+	KittyTopUp(member string, amount int64) (string, error)
+	KittySpend(amount int64, beneficiaries []string) (string, error)
+	ShowKitty() []string
+	SettleUp() ([]string, error)
+	AddBill(bill model.Bill) (string, error)
+	SpendPeriod(amount int64, payer string, from, to time.Time, beneficiaries []string) (string, error)
+	DefineSplit(name string, weights map[string]int64) (string, error)
+	AddWeightedExpense(amount int64, payer, split string) (string, error)
+	ImportTransaction(transaction model.Transaction) (string, error)
+	Check() []string
+	Explain(from, to string) (model.Explanation, error)
+	ClosePeriod(month time.Time) ([]string, error)
+	ReopenPeriod(month time.Time, reason string) (string, error)
+	ShowAudit() []string
 }
 
 // TerminalCmd encapsulates the command execution logic.
@@ -45,45 +93,447 @@ func NewTerminalCmd(housemateService HousemateService, trackerService TrackerSer
 func (t *TerminalCmd) ExecuteCommand(command model.Command) string {
 	switch command.CommandType {
 	case model.MOVE_IN:
-		return t.handleMoveIn(command.Arguments[0])
+		return t.handleMoveIn(command.Arguments)
 	case model.MOVE_OUT:
-		return t.handleMoveOut(command.Arguments[0])
+		return t.handleMoveOut(command.Arguments)
 	case model.SPEND:
 		return t.handleSpend(command.Arguments)
 	case model.CLEAR_DUES:
 		return t.handleClearDues(command.Arguments)
 	case model.DUES:
-		return t.handleDues(command.Arguments[0])
+		return t.handleDues(command.Arguments)
+	case model.SPEND_MULTI:
+		return t.handleSpendMulti(command.Arguments)
+	case model.LEND:
+		return t.handleLend(command.Arguments)
+	case model.PAY_FOR:
+		return t.handlePayFor(command.Arguments)
+	case model.REFUND:
+		return t.handleRefund(command.Arguments)
+	case model.REFUND_EXPENSE:
+		return t.handleRefundExpense(command.Arguments)
+	case model.PAY:
+		return t.handlePay(command.Arguments)
+	case model.DEFINE_SPLIT:
+		return t.handleDefineSplit(command.Arguments)
+	case model.DATE:
+		return t.handleDate(command.Arguments)
+	case model.AWAY:
+		return t.handleAway(command.Arguments)
+	case model.SPEND_PERIOD:
+		return t.handleSpendPeriod(command.Arguments)
+	case model.BILL:
+		return t.handleBill(command)
+	case model.KITTY_TOPUP:
+		return t.handleKittyTopUp(command.Arguments)
+	case model.KITTY_SPEND:
+		return t.handleKittySpend(command.Arguments)
+	case model.KITTY_BALANCE:
+		return formatDues(t.TrackerService.ShowKitty())
+	case model.SETTLE_UP:
+		return t.handleSettleUp()
+	case model.DEPOSITS:
+		return formatDues(t.HousemateService.ShowDeposits())
+	case model.FORMER:
+		return formatDues(t.HousemateService.ShowFormer())
+	case model.SET:
+		return t.handleSet(command.Arguments)
+	case model.HISTORY:
+		return formatDues(t.TrackerService.ShowHistory())
+	case model.CHECK:
+		return t.handleCheck()
+	case model.EXPLAIN:
+		return t.handleExplain(command.Arguments)
+	case model.CLOSE_PERIOD:
+		return t.handleClosePeriod(command.Arguments)
+	case model.REOPEN_PERIOD:
+		return t.handleReopenPeriod(command.Arguments)
+	case model.AUDIT:
+		return formatDues(t.TrackerService.ShowAudit())
 	default:
 		return InvalidCommandMessage + string(command.CommandType)
 	}
 }
 
 // handleMoveIn processes the MOVE_IN command.
-func (t *TerminalCmd) handleMoveIn(housemate string) string {
-	result, err := t.HousemateService.MoveIn(housemate)
-	return t.processResult(result, err)
+// Format: MOVE_IN <name> [DEPOSIT <amount> TO <holder>]
+func (t *TerminalCmd) handleMoveIn(arguments []string) string {
+	switch {
+	case len(arguments) == 1:
+		result, err := t.HousemateService.MoveIn(arguments[0])
+		return t.processResult(result, err)
+	case len(arguments) == 5 && arguments[1] == DepositKeyword && arguments[3] == ToKeyword:
+		amount, err := parseAmount(arguments[2])
+		if err != nil {
+			return InvalidAmountMessage + arguments[2]
+		}
+		result, err := t.HousemateService.MoveInWithDeposit(arguments[0], arguments[4], amount)
+		return t.processResult(result, err)
+	default:
+		return InvalidCommandMessage + string(model.MOVE_IN)
+	}
+}
+
+// handleCheck processes the CHECK command.
+// Prints SUCCESS when the books are consistent, or FAILURE followed by one line per violation.
+func (t *TerminalCmd) handleCheck() string {
+	violations := t.TrackerService.Check()
+	if len(violations) == 0 {
+		return string(model.SUCCESS)
+	}
+	return formatDues(append([]string{string(model.FAILURE)}, violations...))
+}
+
+// handleExplain processes the EXPLAIN command.
+// Format: EXPLAIN <from> <to> [--format text|json]
+func (t *TerminalCmd) handleExplain(arguments []string) string {
+	format := TextFormat
+	switch {
+	case len(arguments) == 2:
+	case len(arguments) == 4 && arguments[2] == FormatFlag && (arguments[3] == TextFormat || arguments[3] == JSONFormat):
+		format = arguments[3]
+	default:
+		return InvalidCommandMessage + string(model.EXPLAIN)
+	}
+	explanation, err := t.TrackerService.Explain(arguments[0], arguments[1])
+	if err != nil {
+		return err.Error()
+	}
+	if format == JSONFormat {
+		out, err := formatExplanationJSON(explanation)
+		if err != nil {
+			return err.Error()
+		}
+		return out
+	}
+	return formatDues(formatExplanation(explanation))
 }
 
 // handleMoveOut processes the MOVE_OUT command.
-func (t *TerminalCmd) handleMoveOut(housemate string) string {
+// Format: MOVE_OUT <name> [SETTLE | TRANSFER_TO <other>]
+func (t *TerminalCmd) handleMoveOut(arguments []string) string {
+	if len(arguments) == 0 {
+		return InvalidCommandMessage + string(model.MOVE_OUT)
+	}
+	housemate := arguments[0]
+	var settled []string
+	switch {
+	case len(arguments) == 1:
+	case len(arguments) == 2 && arguments[1] == SettleKeyword:
+		if err := t.HousemateService.CheckSettleable(housemate); err != nil {
+			return err.Error()
+		}
+		payments, err := t.TrackerService.SettleMember(housemate)
+		if err != nil {
+			return err.Error()
+		}
+		settled = payments
+	case len(arguments) == 3 && arguments[1] == TransferKeyword:
+		if err := t.HousemateService.CheckSettleable(housemate); err != nil {
+			return err.Error()
+		}
+		released, err := t.TrackerService.TransferDues(housemate, arguments[2])
+		if err != nil {
+			return err.Error()
+		}
+		settled = released
+	default:
+		return InvalidCommandMessage + string(model.MOVE_OUT)
+	}
 	result, err := t.HousemateService.MoveOut(housemate)
-	return t.processResult(result, err)
+	return formatDues(append(settled, t.processResult(result, err)))
 }
 
 // handleSpend processes the SPEND command.
+// Format: SPEND <amount> <payer> <beneficiaries...> or SPEND <amount> <payer> USING <split>
 func (t *TerminalCmd) handleSpend(arguments []string) string {
+	if len(arguments) < 2 {
+		return InvalidCommandMessage + string(model.SPEND)
+	}
 	amount, err := strconv.ParseFloat(arguments[0], FloatBase)
 	if err != nil {
 		return InvalidAmountMessage + arguments[0]
 	}
+	if len(arguments) == 4 && arguments[2] == UsingKeyword {
+		result, err := t.TrackerService.AddWeightedExpense(int64(math.Round(amount)), arguments[1], arguments[3])
+		return t.processResult(result, err)
+	}
 	beneficiaries := arguments[1:]
 	result, err := t.TrackerService.AddExpense(amount, beneficiaries)
 	return t.processResult(result, err)
 }
 
+// handleSpendMulti processes the SPEND_MULTI command.
+// Format: SPEND_MULTI <amount> PAID <payer:amount>... FOR <beneficiaries...>
+func (t *TerminalCmd) handleSpendMulti(arguments []string) string {
+	forIndex := indexOf(arguments, ForKeyword)
+	if len(arguments) < 2 || arguments[1] != PaidKeyword || forIndex < 3 || forIndex == len(arguments)-1 {
+		return InvalidCommandMessage + string(model.SPEND_MULTI)
+	}
+	amount, err := parseAmount(arguments[0])
+	if err != nil {
+		return InvalidAmountMessage + arguments[0]
+	}
+	payers, err := parseMemberAmounts(arguments[2:forIndex])
+	if err != nil {
+		return InvalidAmountMessage + err.Error()
+	}
+	result, err := t.TrackerService.AddMultiPayerExpense(amount, payers, arguments[forIndex+1:])
+	return t.processResult(result, err)
+}
+
+// handleLend processes the LEND command.
+// Format: LEND <amount> <lender> <borrower>
+func (t *TerminalCmd) handleLend(arguments []string) string {
+	if len(arguments) != 3 {
+		return InvalidCommandMessage + string(model.LEND)
+	}
+	amount, err := parseAmount(arguments[0])
+	if err != nil {
+		return InvalidAmountMessage + arguments[0]
+	}
+	result, err := t.TrackerService.Lend(amount, arguments[1], arguments[2])
+	return t.processResult(result, err)
+}
+
+// handlePayFor processes the PAY_FOR command.
+// Format: PAY_FOR <amount> <payer> <beneficiaries...>
+func (t *TerminalCmd) handlePayFor(arguments []string) string {
+	if len(arguments) < 3 {
+		return InvalidCommandMessage + string(model.PAY_FOR)
+	}
+	amount, err := parseAmount(arguments[0])
+	if err != nil {
+		return InvalidAmountMessage + arguments[0]
+	}
+	result, err := t.TrackerService.PayFor(amount, arguments[1], arguments[2:])
+	return t.processResult(result, err)
+}
+
+// handleRefund processes the REFUND command.
+// Format: REFUND <amount> <receiver> <beneficiaries...>
+func (t *TerminalCmd) handleRefund(arguments []string) string {
+	if len(arguments) < 3 {
+		return InvalidCommandMessage + string(model.REFUND)
+	}
+	amount, err := parseAmount(arguments[0])
+	if err != nil {
+		return InvalidAmountMessage + arguments[0]
+	}
+	result, err := t.TrackerService.Refund(amount, arguments[1], arguments[2:])
+	return t.processResult(result, err)
+}
+
+// handleRefundExpense processes the REFUND_EXPENSE command.
+// Format: REFUND_EXPENSE <id>
+func (t *TerminalCmd) handleRefundExpense(arguments []string) string {
+	if len(arguments) != 1 {
+		return InvalidCommandMessage + string(model.REFUND_EXPENSE)
+	}
+	id, err := strconv.Atoi(arguments[0])
+	if err != nil {
+		return InvalidCommandMessage + string(model.REFUND_EXPENSE)
+	}
+	result, err := t.TrackerService.RefundExpense(id)
+	return t.processResult(result, err)
+}
+
+// handlePay processes the PAY command.
+// Format: PAY <member> <amount> [LARGEST_FIRST|OLDEST_FIRST|PROPORTIONAL]
+func (t *TerminalCmd) handlePay(arguments []string) string {
+	if len(arguments) < 2 || len(arguments) > 3 {
+		return InvalidCommandMessage + string(model.PAY)
+	}
+	amount, err := parseAmount(arguments[1])
+	if err != nil {
+		return InvalidAmountMessage + arguments[1]
+	}
+	strategy := model.LARGEST_FIRST
+	if len(arguments) == 3 {
+		strategy = model.AllocationStrategy(arguments[2])
+	}
+	switch strategy {
+	case model.LARGEST_FIRST, model.OLDEST_FIRST, model.PROPORTIONAL:
+	default:
+		return InvalidCommandMessage + string(model.PAY)
+	}
+	result, err := t.TrackerService.Pay(arguments[0], amount, strategy)
+	if err != nil {
+		return err.Error()
+	}
+	return formatDues(result)
+}
+
+// handleDefineSplit processes the DEFINE_SPLIT command.
+// Format: DEFINE_SPLIT <name> <member:weight>...
+func (t *TerminalCmd) handleDefineSplit(arguments []string) string {
+	if len(arguments) < 2 {
+		return InvalidCommandMessage + string(model.DEFINE_SPLIT)
+	}
+	weights, err := parseMemberAmounts(arguments[1:])
+	if err != nil {
+		return InvalidAmountMessage + err.Error()
+	}
+	result, err := t.TrackerService.DefineSplit(arguments[0], weights)
+	return t.processResult(result, err)
+}
+
+// handleDate processes the DATE command.
+// Format: DATE <yyyy-mm-dd>
+func (t *TerminalCmd) handleDate(arguments []string) string {
+	if len(arguments) != 1 {
+		return InvalidCommandMessage + string(model.DATE)
+	}
+	date, err := time.Parse(model.DateLayout, arguments[0])
+	if err != nil {
+		return InvalidDateMessage + arguments[0]
+	}
+	result, err := t.HousemateService.SetDate(date)
+	return t.processResult(result, err)
+}
+
+// handleAway processes the AWAY command.
+// Format: AWAY <member> <from> <to>
+func (t *TerminalCmd) handleAway(arguments []string) string {
+	if len(arguments) != 3 {
+		return InvalidCommandMessage + string(model.AWAY)
+	}
+	from, to, message := parsePeriod(arguments[1], arguments[2])
+	if message != "" {
+		return message
+	}
+	result, err := t.HousemateService.MarkAway(arguments[0], from, to)
+	return t.processResult(result, err)
+}
+
+// handleSpendPeriod processes the SPEND_PERIOD command.
+// Format: SPEND_PERIOD <amount> <payer> <from> <to> <beneficiaries...>
+func (t *TerminalCmd) handleSpendPeriod(arguments []string) string {
+	if len(arguments) < 5 {
+		return InvalidCommandMessage + string(model.SPEND_PERIOD)
+	}
+	amount, err := parseAmount(arguments[0])
+	if err != nil {
+		return InvalidAmountMessage + arguments[0]
+	}
+	from, to, message := parsePeriod(arguments[2], arguments[3])
+	if message != "" {
+		return message
+	}
+	result, err := t.TrackerService.SpendPeriod(amount, arguments[1], from, to, arguments[4:])
+	return t.processResult(result, err)
+}
+
+// handleBill processes a BILL block.
+// Format: BILL <payer>, then lines of ITEM <price> <participants...>, TAX <amount>,
+// TIP <amount> or DISCOUNT <amount>, closed by END_BILL.
+func (t *TerminalCmd) handleBill(command model.Command) string {
+	if len(command.Arguments) != 1 {
+		return InvalidCommandMessage + string(model.BILL)
+	}
+	bill := model.Bill{Payer: command.Arguments[0]}
+	for _, fields := range command.Body {
+		if len(fields) < 2 {
+			return InvalidCommandMessage + string(model.BILL)
+		}
+		amount, err := parseAmount(fields[1])
+		if err != nil {
+			return InvalidAmountMessage + fields[1]
+		}
+		bill.Lines = append(bill.Lines, model.BillLine{
+			Kind:         model.BillLineKind(fields[0]),
+			Amount:       amount,
+			Participants: fields[2:],
+		})
+	}
+	result, err := t.TrackerService.AddBill(bill)
+	return t.processResult(result, err)
+}
+
+// handleKittyTopUp processes the KITTY_TOPUP command.
+// Format: KITTY_TOPUP <member> <amount>
+func (t *TerminalCmd) handleKittyTopUp(arguments []string) string {
+	if len(arguments) != 2 {
+		return InvalidCommandMessage + string(model.KITTY_TOPUP)
+	}
+	amount, err := parseAmount(arguments[1])
+	if err != nil {
+		return InvalidAmountMessage + arguments[1]
+	}
+	result, err := t.TrackerService.KittyTopUp(arguments[0], amount)
+	return t.processResult(result, err)
+}
+
+// handleKittySpend processes the KITTY_SPEND command.
+// Format: KITTY_SPEND <amount> <beneficiaries...>
+func (t *TerminalCmd) handleKittySpend(arguments []string) string {
+	if len(arguments) < 2 {
+		return InvalidCommandMessage + string(model.KITTY_SPEND)
+	}
+	amount, err := parseAmount(arguments[0])
+	if err != nil {
+		return InvalidAmountMessage + arguments[0]
+	}
+	result, err := t.TrackerService.KittySpend(amount, arguments[1:])
+	return t.processResult(result, err)
+}
+
+// handleSettleUp processes the SETTLE_UP command.
+func (t *TerminalCmd) handleSettleUp() string {
+	payouts, err := t.TrackerService.SettleUp()
+	if err != nil {
+		return err.Error()
+	}
+	return formatDues(append(payouts, string(model.SUCCESS)))
+}
+
+// handleClosePeriod processes the CLOSE_PERIOD command.
+// Format: CLOSE_PERIOD <yyyy-mm>
+func (t *TerminalCmd) handleClosePeriod(arguments []string) string {
+	if len(arguments) != 1 {
+		return InvalidCommandMessage + string(model.CLOSE_PERIOD)
+	}
+	month, err := time.Parse(model.MonthLayout, arguments[0])
+	if err != nil {
+		return InvalidDateMessage + arguments[0]
+	}
+	balances, err := t.TrackerService.ClosePeriod(month)
+	if err != nil {
+		return err.Error()
+	}
+	return formatDues(append(balances, string(model.SUCCESS)))
+}
+
+// handleReopenPeriod processes the REOPEN_PERIOD command.
+// Format: REOPEN_PERIOD <yyyy-mm> <reason...>
+func (t *TerminalCmd) handleReopenPeriod(arguments []string) string {
+	if len(arguments) < 2 {
+		return InvalidCommandMessage + string(model.REOPEN_PERIOD)
+	}
+	month, err := time.Parse(model.MonthLayout, arguments[0])
+	if err != nil {
+		return InvalidDateMessage + arguments[0]
+	}
+	result, err := t.TrackerService.ReopenPeriod(month, strings.Join(arguments[1:], " "))
+	return t.processResult(result, err)
+}
+
+// handleSet processes the SET command.
+// Format: SET <setting> <value>
+func (t *TerminalCmd) handleSet(arguments []string) string {
+	if len(arguments) != 2 {
+		return InvalidCommandMessage + string(model.SET)
+	}
+	result, err := t.HousemateService.ConfigureSetting(arguments[0], arguments[1])
+	return t.processResult(result, err)
+}
+
 // handleClearDues processes the CLEAR_DUES command.
+// Format: CLEAR_DUE <from> <to> <amount>
 func (t *TerminalCmd) handleClearDues(arguments []string) string {
+	if len(arguments) != 3 {
+		return InvalidCommandMessage + string(model.CLEAR_DUES)
+	}
 	amount, err := strconv.ParseInt(arguments[2], IntBase, IntBitSize)
 	if err != nil {
 		return InvalidAmountMessage + arguments[2]
@@ -93,8 +543,12 @@ func (t *TerminalCmd) handleClearDues(arguments []string) string {
 }
 
 // handleDues processes the DUES command.
-func (t *TerminalCmd) handleDues(housemate string) string {
-	result, err := t.TrackerService.ShowDues(housemate)
+// Format: DUES <name>
+func (t *TerminalCmd) handleDues(arguments []string) string {
+	if len(arguments) != 1 {
+		return InvalidCommandMessage + string(model.DUES)
+	}
+	result, err := t.TrackerService.ShowDues(arguments[0])
 	if err != nil {
 		return err.Error()
 	}
@@ -122,3 +576,54 @@ func formatDues(dues []string) string {
 	}
 	return out
 }
+
+// parseAmount parses a monetary amount, rounding fractional values to the nearest unit.
+func parseAmount(value string) (int64, error) {
+	amount, err := strconv.ParseFloat(value, FloatBase)
+	if err != nil {
+		return 0, err
+	}
+	return int64(math.Round(amount)), nil
+}
+
+// parsePeriod parses the first and last day of a period. On failure it returns the
+// message to print instead.
+func parsePeriod(fromValue, toValue string) (time.Time, time.Time, string) {
+	from, err := time.Parse(model.DateLayout, fromValue)
+	if err != nil {
+		return time.Time{}, time.Time{}, InvalidDateMessage + fromValue
+	}
+	to, err := time.Parse(model.DateLayout, toValue)
+	if err != nil {
+		return time.Time{}, time.Time{}, InvalidDateMessage + toValue
+	}
+	return from, to, ""
+}
+
+// parseMemberAmounts parses arguments of the form <name>:<amount> into a map.
+// The returned error carries the offending argument.
+func parseMemberAmounts(arguments []string) (map[string]int64, error) {
+	amounts := make(map[string]int64, len(arguments))
+	for _, argument := range arguments {
+		parts := strings.SplitN(argument, AmountSeparator, 2)
+		if len(parts) != 2 {
+			return nil, errors.New(argument)
+		}
+		amount, err := parseAmount(parts[1])
+		if err != nil {
+			return nil, errors.New(argument)
+		}
+		amounts[parts[0]] += amount
+	}
+	return amounts, nil
+}
+
+// indexOf returns the position of the first argument equal to keyword, or -1.
+func indexOf(arguments []string, keyword string) int {
+	for i, argument := range arguments {
+		if argument == keyword {
+			return i
+		}
+	}
+	return -1
+}
@@ -1,6 +1,7 @@
 package expense
 
 import (
+	"encoding/json"
 	"splitwise/global"
 	"splitwise/model"
 	"strings"
@@ -54,13 +55,360 @@ func TestExecuteCommand(t *testing.T) {
 				{"DUES WOODY", "ANDY 850\nBO 0"},
 				{"CLEAR_DUE BO ANDY 500", "650"},
 				{"CLEAR_DUE BO ANDY 2500", "INCORRECT_PAYMENT"},
-				{"MOVE_OUT ANDY", "FAILURE"},
-				{"MOVE_OUT WOODY", "FAILURE"},
-				{"MOVE_OUT BO", "FAILURE"},
+				{"CLEAR_DUE BO ANDY -50", "INVALID_AMOUNT"},
+				{"CLEAR_DUE BO ANDY 0", "INVALID_AMOUNT"},
+				{"MOVE_OUT ANDY", "FAILURE\nWOODY OWES ANDY 850\nBO OWES ANDY 650"},
+				{"MOVE_OUT WOODY", "FAILURE\nWOODY OWES ANDY 850"},
+				{"MOVE_OUT BO", "FAILURE\nBO OWES ANDY 650"},
 				{"CLEAR_DUE BO ANDY 650", "0"},
 				{"MOVE_OUT BO", "SUCCESS"},
 			},
 		},
+		{
+			name: "Test Plan 3",
+			testPlan: []struct {
+				command string
+				output  string
+			}{
+				{"MOVE_IN ANDY", "SUCCESS"},
+				{"MOVE_IN WOODY", "SUCCESS"},
+				{"MOVE_IN BO", "SUCCESS"},
+				{"SPEND_MULTI 9000 PAID ANDY:6000 WOODY:3000 FOR ANDY WOODY BO", "SUCCESS"},
+				{"SPEND_MULTI 9000 PAID ANDY:5000 WOODY:3000 FOR ANDY WOODY BO", "AMOUNT_MISMATCH"},
+				{"SPEND_MULTI 100 PAID ANDY:200 WOODY:-100 FOR ANDY WOODY", "INVALID_AMOUNT"},
+				{"SPEND_MULTI -100 PAID ANDY:-100 FOR ANDY WOODY", "INVALID_AMOUNT"},
+				{"SPEND_MULTI 300 PAID ANDY:300 FOR WOODY REX", "MEMBER_NOT_FOUND"},
+				{"SPEND_MULTI 300 PAID ANDY:300", "Invalid command: SPEND_MULTI"},
+				{"DUES BO", "ANDY 3000\nWOODY 0"},
+				{"DUES WOODY", "ANDY 0\nBO 0"},
+				{"SPEND_MULTI 100 PAID WOODY:60 BO:40 FOR ANDY WOODY BO", "SUCCESS"},
+				{"DUES ANDY", "BO 0\nWOODY 0"},
+				{"DUES BO", "ANDY 2966\nWOODY 27"},
+				{"DUES WOODY", "ANDY 0\nBO 0"},
+			},
+		},
+		{
+			name: "Test Plan 4",
+			testPlan: []struct {
+				command string
+				output  string
+			}{
+				{"MOVE_IN ANDY", "SUCCESS"},
+				{"MOVE_IN WOODY", "SUCCESS"},
+				{"MOVE_IN BO", "SUCCESS"},
+				{"LEND 500 ANDY WOODY", "SUCCESS"},
+				{"LEND 500 ANDY REX", "MEMBER_NOT_FOUND"},
+				{"LEND 500 ANDY", "Invalid command: LEND"},
+				{"LEND -50 ANDY WOODY", "INVALID_AMOUNT"},
+				{"PAY_FOR 0 BO ANDY", "INVALID_AMOUNT"},
+				{"DUES WOODY", "ANDY 500\nBO 0"},
+				{"PAY_FOR 301 BO ANDY WOODY", "SUCCESS"},
+				{"DUES ANDY", "BO 0\nWOODY 0"},
+				{"DUES WOODY", "ANDY 349\nBO 301"},
+				{"SPEND 300 ANDY BO", "SUCCESS"},
+				{"DUES WOODY", "ANDY 499\nBO 151"},
+				{"CLEAR_DUE WOODY BO 151", "0"},
+				{"HISTORY", "1 LEND 500 PAID ANDY:500 FOR WOODY:500\n" +
+					"2 PAY_FOR 301 PAID BO:301 FOR ANDY:151 WOODY:150\n" +
+					"3 SPEND 300 PAID ANDY:300 FOR ANDY:150 BO:150\n" +
+					"4 CLEAR_DUE 151 PAID WOODY:151 FOR BO:151"},
+			},
+		},
+		{
+			name: "Test Plan 5",
+			testPlan: []struct {
+				command string
+				output  string
+			}{
+				{"MOVE_IN ANDY", "SUCCESS"},
+				{"MOVE_IN WOODY", "SUCCESS"},
+				{"MOVE_IN BO", "SUCCESS"},
+				{"SPEND 3000 ANDY WOODY BO", "SUCCESS"},
+				{"REFUND 900 ANDY ANDY WOODY BO", "SUCCESS"},
+				{"DUES WOODY", "ANDY 700\nBO 0"},
+				{"REFUND_EXPENSE 1", "SUCCESS"},
+				{"DUES ANDY", "BO 300\nWOODY 300"},
+				{"DUES WOODY", "ANDY 0\nBO 0"},
+				{"REFUND_EXPENSE 1", "INVALID_REFUND"},
+				{"REFUND_EXPENSE 2", "INVALID_REFUND"},
+				{"REFUND_EXPENSE 9", "TRANSACTION_NOT_FOUND"},
+				{"REFUND 900 ANDY REX", "MEMBER_NOT_FOUND"},
+				{"REFUND -900 ANDY WOODY", "INVALID_AMOUNT"},
+				{"HISTORY", "1 SPEND 3000 PAID ANDY:3000 FOR ANDY:1000 BO:1000 WOODY:1000\n" +
+					"2 REFUND 900 PAID ANDY:300 BO:300 WOODY:300 FOR ANDY:900\n" +
+					"3 REFUND 3000 PAID ANDY:1000 BO:1000 WOODY:1000 FOR ANDY:3000 OF 1"},
+			},
+		},
+		{
+			name: "Test Plan 6",
+			testPlan: []struct {
+				command string
+				output  string
+			}{
+				{"MOVE_IN ANDY", "SUCCESS"},
+				{"MOVE_IN WOODY", "SUCCESS"},
+				{"MOVE_IN BO", "SUCCESS"},
+				{"SPEND 1950 ANDY WOODY BO", "SUCCESS"},
+				{"CLEAR_DUE WOODY ANDY 700", "INCORRECT_PAYMENT"},
+				{"SET OVERPAYMENT MAYBE", "INVALID_SETTING"},
+				{"SET ROUNDING UP", "INVALID_SETTING"},
+				{"SET OVERPAYMENT CREDIT", "SUCCESS"},
+				{"CLEAR_DUE WOODY ANDY 700", "0 CREDIT 50"},
+				{"DUES BO", "ANDY 600\nWOODY 50"},
+				{"DUES WOODY", "ANDY 0\nBO 0"},
+				{"SPEND 300 BO WOODY", "SUCCESS"},
+				{"DUES WOODY", "ANDY 100\nBO 0"},
+				{"CLEAR_DUE BO ANDY 500", "0"},
+				{"DUES BO", "ANDY 0\nWOODY 0"},
+			},
+		},
+		{
+			name: "Test Plan 7",
+			testPlan: []struct {
+				command string
+				output  string
+			}{
+				{"MOVE_IN ANDY", "SUCCESS"},
+				{"MOVE_IN WOODY", "SUCCESS"},
+				{"MOVE_IN BO", "SUCCESS"},
+				{"SPEND 600 WOODY BO", "SUCCESS"},
+				{"SPEND 1000 ANDY BO", "SUCCESS"},
+				{"DUES BO", "ANDY 500\nWOODY 300"},
+				{"PAY BO 100 SOMETIMES", "Invalid command: PAY"},
+				{"PAY REX 100", "MEMBER_NOT_FOUND"},
+				{"PAY BO 1000", "INCORRECT_PAYMENT"},
+				{"PAY BO -100", "INVALID_AMOUNT"},
+				{"PAY BO 0", "INVALID_AMOUNT"},
+				{"PAY BO 400 PROPORTIONAL", "ANDY 250\nWOODY 150"},
+				{"PAY BO 200 OLDEST_FIRST", "WOODY 150\nANDY 50"},
+				{"PAY BO 100", "ANDY 100"},
+				{"SET OVERPAYMENT CREDIT", "SUCCESS"},
+				{"PAY BO 150", "ANDY 150\nCREDIT 50"},
+				{"DUES ANDY", "BO 50\nWOODY 0"},
+			},
+		},
+		{
+			name: "Test Plan 8",
+			testPlan: []struct {
+				command string
+				output  string
+			}{
+				{"MOVE_IN ANDY", "SUCCESS"},
+				{"MOVE_IN WOODY", "SUCCESS"},
+				{"MOVE_IN BO", "SUCCESS"},
+				{"SPEND 3000 ANDY WOODY BO", "SUCCESS"},
+				{"MOVE_OUT BO LATER", "Invalid command: MOVE_OUT"},
+				{"MOVE_OUT BO TRANSFER_TO BO", "INVALID_TRANSFER"},
+				{"MOVE_OUT BO TRANSFER_TO REX", "MEMBER_NOT_FOUND"},
+				{"MOVE_OUT BO TRANSFER_TO WOODY", "SUCCESS"},
+				{"DUES WOODY", "ANDY 2000"},
+				{"MOVE_IN BO", "SUCCESS"},
+				{"SPEND 300 BO ANDY WOODY", "SUCCESS"},
+				{"MOVE_OUT ANDY SETTLE", "WOODY PAID ANDY 1900\nSUCCESS"},
+				{"DUES WOODY", "BO 200"},
+				{"HISTORY", "1 SPEND 3000 PAID ANDY:3000 FOR ANDY:1000 BO:1000 WOODY:1000\n" +
+					"2 TRANSFER 1000 PAID BO:1000 FOR WOODY:1000\n" +
+					"3 SPEND 300 PAID BO:300 FOR ANDY:100 BO:100 WOODY:100\n" +
+					"4 CLEAR_DUE 1900 PAID WOODY:1900 FOR ANDY:1900"},
+			},
+		},
+		{
+			name: "Test Plan 9",
+			testPlan: []struct {
+				command string
+				output  string
+			}{
+				{"MOVE_IN ANDY", "SUCCESS"},
+				{"MOVE_IN WOODY", "SUCCESS"},
+				{"MOVE_IN BO", "SUCCESS"},
+				{"FORMER", ""},
+				{"SPEND 300 BO WOODY", "SUCCESS"},
+				{"CLEAR_DUE WOODY BO 150", "0"},
+				{"MOVE_OUT BO", "SUCCESS"},
+				{"MOVE_OUT ANDY", "SUCCESS"},
+				{"FORMER", "ANDY\nBO"},
+				{"SPEND 300 WOODY BO", "MEMBER_NOT_FOUND"},
+				{"MOVE_IN REX", "SUCCESS"},
+				{"MOVE_IN BO", "SUCCESS"},
+				{"MOVE_IN ANDY", "HOUSEFUL"},
+				{"FORMER", "ANDY"},
+				{"HISTORY", "1 SPEND 300 PAID BO:300 FOR BO:150 WOODY:150\n" +
+					"2 CLEAR_DUE 150 PAID WOODY:150 FOR BO:150"},
+			},
+		},
+		{
+			name: "Test Plan 10",
+			testPlan: []struct {
+				command string
+				output  string
+			}{
+				{"MOVE_IN ANDY", "SUCCESS"},
+				{"MOVE_IN WOODY DEPOSIT 500 TO REX", "MEMBER_NOT_FOUND"},
+				{"MOVE_IN WOODY DEPOSIT 0 TO ANDY", "INVALID_DEPOSIT"},
+				{"MOVE_IN WOODY DEPOSIT 500 TO ANDY", "SUCCESS"},
+				{"MOVE_IN BO DEPOSIT 400", "Invalid command: MOVE_IN"},
+				{"MOVE_IN BO DEPOSIT 400 TO ANDY", "SUCCESS"},
+				{"DEPOSITS", "BO DEPOSIT 400 TO ANDY\nWOODY DEPOSIT 500 TO ANDY"},
+				{"SPEND 900 WOODY ANDY BO", "SUCCESS"},
+				{"MOVE_OUT BO", "FAILURE\nBO OWES WOODY 300\nANDY HOLDS DEPOSIT OF BO 400"},
+				{"MOVE_OUT BO SETTLE", "ANDY RELEASED DEPOSIT OF BO 400\nANDY PAID BO 100\nSUCCESS"},
+				{"DUES ANDY", "WOODY 600"},
+				{"MOVE_OUT ANDY", "FAILURE\nANDY OWES WOODY 600\nANDY HOLDS DEPOSIT OF WOODY 500"},
+				{"MOVE_OUT ANDY SETTLE", "FAILURE\nANDY HOLDS DEPOSIT OF WOODY 500"},
+				{"DUES ANDY", "WOODY 600"},
+				{"MOVE_OUT WOODY TRANSFER_TO ANDY", "ANDY RELEASED DEPOSIT OF WOODY 500\nSUCCESS"},
+				{"DEPOSITS", ""},
+				{"MOVE_OUT ANDY", "SUCCESS"},
+				{"HISTORY", "1 DEPOSIT 500 PAID WOODY:500 FOR ANDY:500\n" +
+					"2 DEPOSIT 400 PAID BO:400 FOR ANDY:400\n" +
+					"3 SPEND 900 PAID WOODY:900 FOR ANDY:300 BO:300 WOODY:300\n" +
+					"4 DEPOSIT_RELEASE 400 PAID BO:400 FOR ANDY:400\n" +
+					"5 CLEAR_DUE 100 PAID ANDY:100 FOR BO:100\n" +
+					"6 DEPOSIT_RELEASE 500 PAID WOODY:500 FOR ANDY:500\n" +
+					"7 TRANSFER 1100 PAID ANDY:1100 FOR WOODY:1100"},
+			},
+		},
+		{
+			name: "Test Plan 11",
+			testPlan: []struct {
+				command string
+				output  string
+			}{
+				{"MOVE_IN ANDY", "SUCCESS"},
+				{"MOVE_IN KITTY", "RESERVED_NAME"},
+				{"MOVE_IN WOODY", "SUCCESS"},
+				{"MOVE_IN BO", "SUCCESS"},
+				{"KITTY_TOPUP ANDY 600", "SUCCESS"},
+				{"KITTY_TOPUP WOODY 300", "SUCCESS"},
+				{"KITTY_TOPUP REX 100", "MEMBER_NOT_FOUND"},
+				{"KITTY_TOPUP BO -100", "INVALID_AMOUNT"},
+				{"KITTY_TOPUP BO 0", "INVALID_AMOUNT"},
+				{"KITTY_SPEND 1000 ANDY WOODY BO", "INSUFFICIENT_KITTY"},
+				{"KITTY_SPEND -600 ANDY", "INVALID_AMOUNT"},
+				{"KITTY_SPEND 0 ANDY", "INVALID_AMOUNT"},
+				{"KITTY_SPEND 600 ANDY WOODY BO", "SUCCESS"},
+				{"KITTY_BALANCE", "KITTY 300\nANDY 400\nBO -200\nWOODY 100"},
+				{"MOVE_OUT BO", "FAILURE\nBO HAS KITTY SHARE -200"},
+				{"MOVE_OUT BO SETTLE", "FAILURE\nBO HAS KITTY SHARE -200"},
+				{"MOVE_OUT BO TRANSFER_TO ANDY", "FAILURE\nBO HAS KITTY SHARE -200"},
+				{"SETTLE_UP", "KITTY PAID ANDY 300\nSUCCESS"},
+				{"KITTY_BALANCE", "KITTY 0"},
+				{"DUES BO", "ANDY 100\nWOODY 100"},
+				{"HISTORY", "1 KITTY_TOPUP 600 PAID ANDY:600 FOR KITTY:600\n" +
+					"2 KITTY_TOPUP 300 PAID WOODY:300 FOR KITTY:300\n" +
+					"3 KITTY_SPEND 600 PAID KITTY:600 FOR ANDY:200 BO:200 WOODY:200\n" +
+					"4 KITTY_PAYOUT 300 PAID KITTY:300 FOR ANDY:300\n" +
+					"5 KITTY_SETTLE 200 PAID ANDY:100 WOODY:100 FOR BO:200"},
+			},
+		},
+		{
+			name: "Test Plan 12",
+			testPlan: []struct {
+				command string
+				output  string
+			}{
+				{"DATE 2024-03-01", "SUCCESS"},
+				{"MOVE_IN ANDY", "SUCCESS"},
+				{"MOVE_IN WOODY", "SUCCESS"},
+				{"DATE 2024-03-20", "SUCCESS"},
+				{"MOVE_IN BO", "SUCCESS"},
+				{"DATE tomorrow", "Invalid date: tomorrow"},
+				{"DATE 2024-03-31", "SUCCESS"},
+				{"AWAY WOODY 2024-03-01 2024-03-14", "SUCCESS"},
+				{"AWAY WOODY 2024-03-10 2024-03-01", "INVALID_PERIOD"},
+				{"AWAY REX 2024-03-01 2024-03-14", "MEMBER_NOT_FOUND"},
+				{"SPEND_PERIOD 3100 ANDY 2024-03-01 2024-03-31 ANDY WOODY BO", "SUCCESS"},
+				{"DUES WOODY", "ANDY 878\nBO 0"},
+				{"DUES BO", "ANDY 620\nWOODY 0"},
+				{"SPEND_PERIOD 100 ANDY 2024-02-01 2024-02-10 BO", "INVALID_PERIOD"},
+				{"SPEND_PERIOD -100 ANDY 2024-03-01 2024-03-31 BO", "INVALID_AMOUNT"},
+				{"SPEND_PERIOD 100 ANDY 2024-03-31 2024-03-01 BO", "INVALID_PERIOD"},
+				{"SPEND_PERIOD 100 ANDY 2024-03-xx 2024-03-31 BO", "Invalid date: 2024-03-xx"},
+				{"HISTORY", "1 SPEND_PERIOD 3100 PAID ANDY:3100 FOR ANDY:1602 BO:620 WOODY:878 ON 2024-03-31"},
+			},
+		},
+		{
+			name: "Test Plan 13",
+			testPlan: []struct {
+				command string
+				output  string
+			}{
+				{"MOVE_IN ANDY", "SUCCESS"},
+				{"MOVE_IN WOODY", "SUCCESS"},
+				{"MOVE_IN BO", "SUCCESS"},
+				{"DEFINE_SPLIT RENT ANDY:3 WOODY:2 REX:2", "MEMBER_NOT_FOUND"},
+				{"DEFINE_SPLIT RENT ANDY:3 WOODY:0", "INVALID_SPLIT"},
+				{"DEFINE_SPLIT RENT ANDY:3 WOODY", "Invalid amount: WOODY"},
+				{"DEFINE_SPLIT RENT ANDY:3 WOODY:2 BO:2", "SUCCESS"},
+				{"SPEND 30000 ANDY USING POWER", "SPLIT_NOT_FOUND"},
+				{"SPEND -30000 ANDY USING RENT", "INVALID_AMOUNT"},
+				{"SPEND 30000 ANDY USING RENT", "SUCCESS"},
+				{"DUES BO", "ANDY 8572\nWOODY 0"},
+				{"DUES WOODY", "ANDY 8571\nBO 0"},
+				{"HISTORY", "1 SPEND 30000 PAID ANDY:30000 FOR ANDY:12857 BO:8572 WOODY:8571 USING RENT ANDY:3 BO:2 WOODY:2"},
+				{"CLEAR_DUE BO ANDY 8572", "0"},
+				{"MOVE_OUT BO", "SUCCESS"},
+				{"SPEND 700 ANDY USING RENT", "MEMBER_NOT_FOUND"},
+			},
+		},
+		{
+			name: "Test Plan 14",
+			testPlan: []struct {
+				command string
+				output  string
+			}{
+				{"MOVE_IN ANDY", "SUCCESS"},
+				{"MOVE_IN WOODY", "SUCCESS"},
+				{"MOVE_IN BO", "SUCCESS"},
+				{"SPEND 900 ANDY WOODY", "SUCCESS"},
+				{"LEND 300 WOODY BO", "SUCCESS"},
+				{"CHECK", "SUCCESS"},
+				{"EXPLAIN BO ANDY", "BO OWES ANDY 300 RAW 0\n" +
+					"TRANSACTION 1 SPEND 900 ANDY:450 BO:0\n" +
+					"TRANSACTION 2 LEND 300 ANDY:0 BO:-300\n" +
+					"BALANCE ANDY 450\nBALANCE BO -300\nBALANCE WOODY -150\n" +
+					"STEP 1 BO PAYS ANDY 300 BALANCES -300 450\n" +
+					"STEP 2 WOODY PAYS ANDY 150 BALANCES -150 150"},
+				{"EXPLAIN BO REX", "MEMBER_NOT_FOUND"},
+				{"EXPLAIN BO ANDY --format xml", "Invalid command: EXPLAIN"},
+			},
+		},
+		{
+			name: "Test Plan 15",
+			testPlan: []struct {
+				command string
+				output  string
+			}{
+				{"MOVE_IN ANDY", "SUCCESS"},
+				{"MOVE_IN WOODY", "SUCCESS"},
+				{"DATE 2026-02-20", "SUCCESS"},
+				{"SPEND 600 ANDY WOODY", "SUCCESS"},
+				{"DATE 2026-03-02", "SUCCESS"},
+				{"SPEND 200 WOODY ANDY", "SUCCESS"},
+				{"CLOSE_PERIOD 2026-02", "ANDY 300\nWOODY -300\nSUCCESS"},
+				{"CLOSE_PERIOD 2026-02", "PERIOD_CLOSED"},
+				{"CLOSE_PERIOD 2026-2x", "Invalid date: 2026-2x"},
+				{"DATE 2026-02-25", "SUCCESS"},
+				{"SPEND 100 ANDY WOODY", "PERIOD_CLOSED"},
+				{"CLEAR_DUE WOODY ANDY 100", "PERIOD_CLOSED"},
+				{"KITTY_TOPUP ANDY 100", "PERIOD_CLOSED"},
+				{"KITTY_BALANCE", "KITTY 0"},
+				{"MOVE_IN BO DEPOSIT 100 TO ANDY", "PERIOD_CLOSED"},
+				{"DATE 2026-01-15", "SUCCESS"},
+				{"SPEND 400 WOODY ANDY", "PERIOD_CLOSED"},
+				{"DUES WOODY", "ANDY 200"},
+				{"CLOSE_PERIOD 2026-01", "ANDY 0\nWOODY 0\nSUCCESS"},
+				{"REOPEN_PERIOD 2026-02", "Invalid command: REOPEN_PERIOD"},
+				{"REOPEN_PERIOD 2026-04 missed receipt", "PERIOD_NOT_CLOSED"},
+				{"REOPEN_PERIOD 2026-01 wrong month", "PERIOD_CLOSED"},
+				{"DATE 2026-02-25", "SUCCESS"},
+				{"REOPEN_PERIOD 2026-02 missed receipt", "SUCCESS"},
+				{"SPEND 100 ANDY WOODY", "SUCCESS"},
+				{"DUES WOODY", "ANDY 250"},
+				{"AUDIT", "2026-03-02 CLOSE_PERIOD 2026-02\n2026-01-15 CLOSE_PERIOD 2026-01\n" +
+					"2026-02-25 REOPEN_PERIOD 2026-02 missed receipt"},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -83,3 +431,82 @@ func TestExecuteCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestExecuteBillCommand(t *testing.T) {
+	globalStorage := global.NewGlobalMapStorage()
+	terminalCmd := NewTerminalCmd(NewHousemateServiceImpl(globalStorage), NewTrackerServiceImpl(globalStorage))
+	for _, name := range []string{"ANDY", "WOODY", "BO"} {
+		terminalCmd.ExecuteCommand(model.Command{CommandType: model.MOVE_IN, Arguments: []string{name}})
+	}
+
+	tests := []struct {
+		name   string
+		payer  string
+		body   []string
+		output string
+	}{
+		{"No items", "ANDY", []string{"TAX 100"}, "INVALID_BILL"},
+		{"Unknown line", "ANDY", []string{"ITEM 100 ANDY", "SERVICE 10"}, "INVALID_BILL"},
+		{"Discount larger than items", "ANDY", []string{"ITEM 100 ANDY", "DISCOUNT 100"}, "INVALID_BILL"},
+		{"Unknown participant", "ANDY", []string{"ITEM 100 REX"}, "MEMBER_NOT_FOUND"},
+		{"Invalid amount", "ANDY", []string{"ITEM ten ANDY"}, "Invalid amount: ten"},
+		{"Itemized bill", "ANDY", []string{
+			"ITEM 1200 ANDY BO",
+			"ITEM 900 ANDY WOODY BO",
+			"ITEM 500 WOODY",
+			"TAX 130",
+			"TIP 260",
+			"DISCOUNT 100",
+		}, "SUCCESS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command := model.Command{CommandType: model.BILL, Arguments: []string{tt.payer}}
+			for _, line := range tt.body {
+				command.Body = append(command.Body, strings.Fields(line))
+			}
+			if result := terminalCmd.ExecuteCommand(command); result != tt.output {
+				t.Errorf("Expected output: %s, but got: %s", tt.output, result)
+			}
+		})
+	}
+
+	expectedHistory := "1 BILL 2890 PAID ANDY:2890 FOR ANDY:1000 BO:1001 WOODY:889\n" +
+		"  ITEM 1200 ANDY:600 BO:600\n" +
+		"  ITEM 900 ANDY:300 BO:300 WOODY:300\n" +
+		"  ITEM 500 WOODY:500\n" +
+		"  TAX 130 ANDY:45 BO:45 WOODY:40\n" +
+		"  TIP 260 ANDY:90 BO:90 WOODY:80\n" +
+		"  DISCOUNT 100 ANDY:35 BO:34 WOODY:31"
+	if result := terminalCmd.ExecuteCommand(model.Command{CommandType: model.HISTORY}); result != expectedHistory {
+		t.Errorf("Expected history: %s, but got: %s", expectedHistory, result)
+	}
+	if result := terminalCmd.ExecuteCommand(model.Command{CommandType: model.DUES, Arguments: []string{"BO"}}); result != "ANDY 1001\nWOODY 0" {
+		t.Errorf("Expected BO to owe ANDY 1001, but got: %s", result)
+	}
+}
+
+func TestExplainJSON(t *testing.T) {
+	globalStorage := global.NewGlobalMapStorage()
+	terminalCmd := NewTerminalCmd(NewHousemateServiceImpl(globalStorage), NewTrackerServiceImpl(globalStorage))
+	for _, command := range []string{"MOVE_IN ANDY", "MOVE_IN WOODY", "SPEND 600 ANDY WOODY"} {
+		args := strings.Fields(command)
+		terminalCmd.ExecuteCommand(model.Command{CommandType: model.CommandType(args[0]), Arguments: args[1:]})
+	}
+
+	result := terminalCmd.ExecuteCommand(model.Command{
+		CommandType: model.EXPLAIN,
+		Arguments:   []string{"WOODY", "ANDY", FormatFlag, JSONFormat},
+	})
+	var explanation model.Explanation
+	if err := json.Unmarshal([]byte(result), &explanation); err != nil {
+		t.Fatalf("Expected a JSON document, got %s: %v", result, err)
+	}
+	if explanation.Due != 300 || explanation.RawDue != 300 || len(explanation.Contributions) != 1 || len(explanation.Steps) != 1 {
+		t.Errorf("Expected a due of 300 from one expense settled in one step, got %+v", explanation)
+	}
+	if step := explanation.Steps[0]; step.Debtor != "WOODY" || step.Creditor != "ANDY" || step.Amount != 300 {
+		t.Errorf("Expected WOODY to pay ANDY 300, got %+v", step)
+	}
+}
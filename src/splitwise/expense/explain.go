@@ -0,0 +1,93 @@
+package expense
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"splitwise/model"
+)
+
+// Explain traces the simplified due from owes to: the transactions that moved the balance
+// of either of them, the net balance of every housemate and the settlements chosen when
+// the dues were simplified.
+func (t *TrackerServiceImpl) Explain(from, to string) (model.Explanation, error) {
+	if err := t.validateHousemateExists(from); err != nil {
+		return model.Explanation{}, err
+	}
+	if err := t.validateHousemateExists(to); err != nil {
+		return model.Explanation{}, err
+	}
+
+	contributions := []model.Contribution{}
+	for _, transaction := range t.storage.GetHistory() {
+		if !transaction.Type.AffectsDues() {
+			continue
+		}
+		effects := transaction.Effects()
+		if effects[from] == model.ZERO_DUE && effects[to] == model.ZERO_DUE {
+			continue
+		}
+		contributions = append(contributions, model.Contribution{
+			ID:      transaction.ID,
+			Date:    transaction.Date,
+			Type:    transaction.Type,
+			Amount:  transaction.Amount,
+			Effects: map[string]int64{from: effects[from], to: effects[to]},
+		})
+	}
+
+	steps := t.storage.GetSimplificationSteps()
+	if steps == nil {
+		steps = []model.SimplificationStep{}
+	}
+	return model.Explanation{
+		From:          from,
+		To:            to,
+		Due:           t.storage.GetDue(from, to),
+		RawDue:        t.storage.GetNonShuffledDue(to, from),
+		Contributions: contributions,
+		Balances:      t.storage.GetNetBalances(),
+		Steps:         steps,
+	}, nil
+}
+
+// formatExplanation renders an explanation as lines of text: the due, then one line per
+// contributing transaction, per housemate balance and per simplification step.
+func formatExplanation(explanation model.Explanation) []string {
+	lines := []string{fmt.Sprintf("%s OWES %s %d RAW %d", explanation.From, explanation.To, explanation.Due, explanation.RawDue)}
+	for _, contribution := range explanation.Contributions {
+		line := fmt.Sprintf("TRANSACTION %d %s %d %s", contribution.ID, contribution.Type, contribution.Amount,
+			formatMemberAmounts(contribution.Effects))
+		if !contribution.Date.IsZero() {
+			line += " ON " + contribution.Date.Format(model.DateLayout)
+		}
+		lines = append(lines, line)
+	}
+	for _, name := range sortedNames(explanation.Balances) {
+		lines = append(lines, fmt.Sprintf("BALANCE %s %d", name, explanation.Balances[name]))
+	}
+	for i, step := range explanation.Steps {
+		lines = append(lines, fmt.Sprintf("STEP %d %s PAYS %s %d BALANCES %d %d", i+1, step.Debtor, step.Creditor,
+			step.Amount, step.DebtorBalance, step.CreditorBalance))
+	}
+	return lines
+}
+
+// formatExplanationJSON renders an explanation as an indented JSON document.
+func formatExplanationJSON(explanation model.Explanation) (string, error) {
+	out, err := json.MarshalIndent(explanation, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// sortedNames returns the names of a map of amounts in order.
+func sortedNames(amounts map[string]int64) []string {
+	names := make([]string, 0, len(amounts))
+	for name := range amounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -2,8 +2,11 @@ package expense
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"splitwise/global"
 	"splitwise/model"
+	"time"
 )
 
 type HousemateServiceImpl struct {
@@ -19,6 +22,9 @@ func NewHousemateServiceImpl(storage *global.GlobalMapStorage) *HousemateService
 
 // MoveIn adds a housemate to the house and initializes dues with existing housemates.
 func (h *HousemateServiceImpl) MoveIn(housemate string) (string, error) {
+	if housemate == model.KITTY {
+		return "", errors.New(string(model.RESERVED_NAME))
+	}
 	if h.storage.CheckHousemateExists(housemate) {
 		return "", errors.New(string(model.MEMBER_ALREADY_EXISTS))
 	}
@@ -30,47 +36,174 @@ func (h *HousemateServiceImpl) MoveIn(housemate string) (string, error) {
 	return string(model.SUCCESS), nil
 }
 
+// MoveInWithDeposit adds a housemate who pays a security deposit to the holder on moving in.
+// The deposit is tracked separately from expense dues.
+func (h *HousemateServiceImpl) MoveInWithDeposit(housemate, holder string, amount int64) (string, error) {
+	if !h.storage.CheckHousemateExists(holder) {
+		return "", errors.New(string(model.MEMBER_NOT_FOUND))
+	}
+	if holder == housemate || amount <= 0 {
+		return "", errors.New(string(model.INVALID_DEPOSIT))
+	}
+	if err := h.storage.CheckPeriodOpen(time.Time{}); err != nil {
+		return "", err
+	}
+	result, err := h.MoveIn(housemate)
+	if err != nil {
+		return "", err
+	}
+	h.storage.SetDeposit(housemate, model.Deposit{Holder: holder, Amount: amount})
+	if _, err := h.storage.RecordTransaction(model.Transaction{
+		Type:   model.DEPOSIT_TRANSACTION,
+		Amount: amount,
+		Payers: map[string]int64{housemate: amount},
+		Shares: map[string]int64{holder: amount},
+	}); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// ShowDeposits lists every held deposit as "<member> DEPOSIT <amount> TO <holder>", sorted by member.
+func (h *HousemateServiceImpl) ShowDeposits() []string {
+	deposits := h.storage.GetDeposits()
+	members := make([]string, 0, len(deposits))
+	for member := range deposits {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+
+	result := make([]string, 0, len(members))
+	for _, member := range members {
+		result = append(result, fmt.Sprintf("%s DEPOSIT %d TO %s", member, deposits[member].Amount, deposits[member].Holder))
+	}
+	return result
+}
+
 // MoveOut removes a housemate from the house and clears their dues.
 func (h *HousemateServiceImpl) MoveOut(housemate string) (string, error) {
 	if !h.storage.CheckHousemateExists(housemate) {
 		return "", errors.New(string(model.MEMBER_NOT_FOUND))
 	}
-	if h.hasPendingDue(housemate) {
-		return "", errors.New(string(model.FAILURE))
+	if pending := h.pendingDues(housemate); len(pending) > 0 {
+		return "", errors.New(formatDues(append([]string{string(model.FAILURE)}, pending...)))
 	}
 	h.storage.RemoveHousemate(housemate)
 	return string(model.SUCCESS), nil
 }
 
+// SetDate sets the house date used to stamp moves and transactions from now on.
+func (h *HousemateServiceImpl) SetDate(date time.Time) (string, error) {
+	h.storage.SetToday(date)
+	return string(model.SUCCESS), nil
+}
+
+// MarkAway records that a housemate was away on the days from..to, inclusive.
+func (h *HousemateServiceImpl) MarkAway(housemate string, from, to time.Time) (string, error) {
+	if !h.storage.CheckHousemateExists(housemate) {
+		return "", errors.New(string(model.MEMBER_NOT_FOUND))
+	}
+	if to.Before(from) {
+		return "", errors.New(string(model.INVALID_PERIOD))
+	}
+	h.storage.AddAway(housemate, model.Interval{From: from, To: to})
+	return string(model.SUCCESS), nil
+}
+
+// ShowFormer returns the names of everyone who has moved out and not moved back in.
+func (h *HousemateServiceImpl) ShowFormer() []string {
+	return h.storage.GetFormerHousemateNames()
+}
+
+// ConfigureSetting changes a house-wide setting after checking the value is allowed.
+func (h *HousemateServiceImpl) ConfigureSetting(setting, value string) (string, error) {
+	for _, allowed := range model.AllowedSettingValues[model.Setting(setting)] {
+		if allowed == value {
+			h.storage.SetSetting(model.Setting(setting), value)
+			return string(model.SUCCESS), nil
+		}
+	}
+	return "", errors.New(string(model.INVALID_SETTING))
+}
+
 // isRoomFull checks if the house has reached its maximum capacity.
 func (h *HousemateServiceImpl) isRoomFull() bool {
 	return h.storage.GetNumberOfHousemates() == model.MAX_HOUSEMATES
 }
 
-// hasPendingDue checks if a housemate has any pending dues.
-func (h *HousemateServiceImpl) hasPendingDue(name string) bool {
-	transactions := h.storage.GetTransactions()
+// pendingDues lists every outstanding simplified due the housemate owes or is owed,
+// as "<debtor> OWES <creditor> <amount>", largest first.
+func (h *HousemateServiceImpl) pendingDues(name string) []string {
+	var pending []model.Due
+	for from, dues := range h.storage.GetTransactions() {
+		for to, due := range dues {
+			if due > 0 && (from == name || to == name) {
+				pending = append(pending, model.Due{From: from, To: to, Amount: due})
+			}
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].Amount != pending[j].Amount {
+			return pending[i].Amount > pending[j].Amount
+		}
+		if pending[i].From != pending[j].From {
+			return pending[i].From < pending[j].From
+		}
+		return pending[i].To < pending[j].To
+	})
 
-	// Check if the housemate owes or is owed any positive amount
-	return h.hasPositiveDue(transactions[name]) || h.isOwedPositiveDue(name, transactions)
+	result := make([]string, 0, len(pending))
+	for _, due := range pending {
+		result = append(result, fmt.Sprintf("%s OWES %s %d", due.From, due.To, due.Amount))
+	}
+	result = append(result, h.heldDeposits(name)...)
+	return append(result, h.kittyShare(name)...)
+}
+
+// CheckSettleable reports, as FAILURE followed by one line per blocker, what would still keep
+// the housemate from moving out once their dues are settled or transferred, so that nothing
+// is posted for a move that cannot happen.
+func (h *HousemateServiceImpl) CheckSettleable(housemate string) error {
+	if !h.storage.CheckHousemateExists(housemate) {
+		return errors.New(string(model.MEMBER_NOT_FOUND))
+	}
+	blockers := append(h.depositsHeldFor(housemate), h.kittyShare(housemate)...)
+	if len(blockers) > 0 {
+		return errors.New(formatDues(append([]string{string(model.FAILURE)}, blockers...)))
+	}
+	return nil
 }
 
-// hasPositiveDue checks if the housemate has any positive dues in their transactions.
-func (h *HousemateServiceImpl) hasPositiveDue(transactions map[string]int64) bool {
-	for _, due := range transactions {
-		if due > 0 {
-			return true
+// depositsHeldFor lists the deposits the housemate holds for others, which only their
+// owners' moving out releases, as "<holder> HOLDS DEPOSIT OF <member> <amount>".
+func (h *HousemateServiceImpl) depositsHeldFor(holder string) []string {
+	var result []string
+	for member, deposit := range h.storage.GetDeposits() {
+		if deposit.Holder == holder {
+			result = append(result, fmt.Sprintf("%s HOLDS DEPOSIT OF %s %d", holder, member, deposit.Amount))
 		}
 	}
-	return false
+	sort.Strings(result)
+	return result
+}
+
+// kittyShare lists the housemate's share of the kitty, if any, as "<name> HAS KITTY SHARE <amount>".
+func (h *HousemateServiceImpl) kittyShare(name string) []string {
+	if share := h.storage.GetKittyShares()[name]; share != model.ZERO_DUE {
+		return []string{fmt.Sprintf("%s HAS %s SHARE %d", name, model.KITTY, share)}
+	}
+	return nil
 }
 
-// isOwedPositiveDue checks if any housemate owes the given housemate a positive due.
-func (h *HousemateServiceImpl) isOwedPositiveDue(name string, transactions map[string]map[string]int64) bool {
-	for _, dues := range transactions {
-		if dues[name] > 0 {
-			return true
+// heldDeposits lists the deposits the housemate paid or holds, as "<holder> HOLDS DEPOSIT OF <member> <amount>".
+func (h *HousemateServiceImpl) heldDeposits(name string) []string {
+	deposits := h.storage.GetDeposits()
+	var result []string
+	for member, deposit := range deposits {
+		if member == name || deposit.Holder == name {
+			result = append(result, fmt.Sprintf("%s HOLDS DEPOSIT OF %s %d", deposit.Holder, member, deposit.Amount))
 		}
 	}
-	return false
+	sort.Strings(result)
+	return result
 }
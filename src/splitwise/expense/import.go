@@ -0,0 +1,22 @@
+package expense
+
+import (
+	"errors"
+	"splitwise/model"
+)
+
+// ImportTransaction posts a transaction imported from another tool as it is. Its payers and
+// shares must add up to the same total and every member in it must live in the house.
+func (t *TrackerServiceImpl) ImportTransaction(transaction model.Transaction) (string, error) {
+	var paid, shared int64
+	for _, amount := range transaction.Payers {
+		paid += amount
+	}
+	for _, amount := range transaction.Shares {
+		shared += amount
+	}
+	if paid != shared || paid <= 0 {
+		return "", errors.New(string(model.AMOUNT_MISMATCH))
+	}
+	return t.postTransaction(transaction)
+}
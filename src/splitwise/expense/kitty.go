@@ -0,0 +1,139 @@
+package expense
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"splitwise/model"
+	"time"
+)
+
+// KittyTopUp adds money from a housemate to the house kitty and raises their share of it.
+func (t *TrackerServiceImpl) KittyTopUp(member string, amount int64) (string, error) {
+	if err := t.validateHousemateExists(member); err != nil {
+		return "", err
+	}
+	if err := validateAmount(amount); err != nil {
+		return "", err
+	}
+
+	if _, err := t.storage.RecordTransaction(model.Transaction{
+		Type:   model.KITTY_TOPUP_TRANSACTION,
+		Amount: amount,
+		Payers: map[string]int64{member: amount},
+		Shares: map[string]int64{model.KITTY: amount},
+	}); err != nil {
+		return "", err
+	}
+	t.storage.AdjustKittyShare(member, amount)
+	return string(model.SUCCESS), nil
+}
+
+// KittySpend pays an expense from the house kitty and lowers each beneficiary's share of
+// the kitty by their part of it. A share below zero is money the housemate owes the others.
+func (t *TrackerServiceImpl) KittySpend(amount int64, beneficiaries []string) (string, error) {
+	for _, beneficiary := range beneficiaries {
+		if err := t.validateHousemateExists(beneficiary); err != nil {
+			return "", err
+		}
+	}
+	if err := validateAmount(amount); err != nil {
+		return "", err
+	}
+	if amount > t.storage.GetKittyBalance() {
+		return "", errors.New(string(model.INSUFFICIENT_KITTY))
+	}
+
+	shares := splitAmongBeneficiaries(amount, beneficiaries)
+	if _, err := t.storage.RecordTransaction(model.Transaction{
+		Type:   model.KITTY_SPEND_TRANSACTION,
+		Amount: amount,
+		Payers: map[string]int64{model.KITTY: amount},
+		Shares: shares,
+	}); err != nil {
+		return "", err
+	}
+	for beneficiary, share := range shares {
+		t.storage.AdjustKittyShare(beneficiary, -share)
+	}
+	return string(model.SUCCESS), nil
+}
+
+// ShowKitty returns the money held in the kitty as "KITTY <amount>", followed by each
+// housemate's share of it sorted by name.
+func (t *TrackerServiceImpl) ShowKitty() []string {
+	shares := t.storage.GetKittyShares()
+	names := make([]string, 0, len(shares))
+	for name := range shares {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := []string{fmt.Sprintf("%s %d", model.KITTY, t.storage.GetKittyBalance())}
+	for _, name := range names {
+		result = append(result, fmt.Sprintf("%s %d", name, shares[name]))
+	}
+	return result
+}
+
+// SettleUp closes the house kitty. The money left in it is paid out to the housemates with
+// the largest shares first, and whatever shares remain become dues between housemates.
+// It returns the payouts as "KITTY PAID <member> <amount>".
+func (t *TrackerServiceImpl) SettleUp() ([]string, error) {
+	shares := t.storage.GetKittyShares()
+	for member := range shares {
+		if err := t.validateHousemateExists(member); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.storage.CheckPeriodOpen(time.Time{}); err != nil {
+		return nil, err
+	}
+
+	var result []string
+	cash := t.storage.GetKittyBalance()
+	for _, member := range t.sortMembersByDues(shares) {
+		if cash == model.ZERO_DUE || member.dues <= 0 {
+			break
+		}
+		payout := member.dues
+		if cash < payout {
+			payout = cash
+		}
+		cash -= payout
+		shares[member.name] -= payout
+		if _, err := t.storage.RecordTransaction(model.Transaction{
+			Type:   model.KITTY_PAYOUT_TRANSACTION,
+			Amount: payout,
+			Payers: map[string]int64{model.KITTY: payout},
+			Shares: map[string]int64{member.name: payout},
+		}); err != nil {
+			return nil, err
+		}
+		result = append(result, fmt.Sprintf("%s PAID %s %d", model.KITTY, member.name, payout))
+	}
+
+	settlement := model.Transaction{
+		Type:   model.KITTY_SETTLE_TRANSACTION,
+		Payers: make(map[string]int64),
+		Shares: make(map[string]int64),
+	}
+	for member, share := range shares {
+		if share > 0 {
+			settlement.Payers[member] = share
+			settlement.Amount += share
+		} else if share < 0 {
+			settlement.Shares[member] = -share
+		}
+	}
+	if settlement.Amount > 0 {
+		if _, err := t.postTransaction(settlement); err != nil {
+			return nil, err
+		}
+	}
+
+	for member, share := range t.storage.GetKittyShares() {
+		t.storage.AdjustKittyShare(member, -share)
+	}
+	return result, nil
+}
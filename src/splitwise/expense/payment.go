@@ -0,0 +1,215 @@
+package expense
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"splitwise/model"
+)
+
+// Pay spreads a lump sum paid by a member over their creditors according to the strategy
+// and records one CLEAR_DUE per creditor. It returns the payment made to each creditor,
+// followed by the credit kept when the house accepts overpayments.
+func (t *TrackerServiceImpl) Pay(member string, amount int64, strategy model.AllocationStrategy) ([]string, error) {
+	if err := t.validateHousemateExists(member); err != nil {
+		return nil, err
+	}
+	if err := validateAmount(amount); err != nil {
+		return nil, err
+	}
+
+	payments := t.allocatePayment(t.orderCreditors(member, strategy), amount, strategy)
+	remainder := amount
+	for _, payment := range payments {
+		remainder -= payment.dues
+	}
+	if remainder > 0 {
+		if len(payments) == 0 || t.storage.GetSetting(model.OVERPAYMENT) != model.OVERPAYMENT_CREDIT {
+			return nil, errors.New(string(model.INCORRECT_PAYMENT))
+		}
+		payments[0].dues += remainder
+	}
+
+	result := make([]string, 0, len(payments)+1)
+	for _, payment := range payments {
+		if payment.dues == model.ZERO_DUE {
+			continue
+		}
+		if _, err := t.postTransaction(model.Transaction{
+			Type:   model.PAYMENT_TRANSACTION,
+			Amount: payment.dues,
+			Payers: map[string]int64{member: payment.dues},
+			Shares: map[string]int64{payment.name: payment.dues},
+		}); err != nil {
+			return nil, err
+		}
+		result = append(result, fmt.Sprintf("%s %d", payment.name, payment.dues))
+	}
+	if remainder > 0 {
+		result = append(result, fmt.Sprintf("CREDIT %d", remainder))
+	}
+	return result, nil
+}
+
+// orderCreditors returns everyone the member owes money to, in the order the strategy pays them.
+func (t *TrackerServiceImpl) orderCreditors(member string, strategy model.AllocationStrategy) []Member {
+	var creditors []Member
+	for _, creditor := range t.sortMembersByDues(t.storage.GetAllDues(member)) {
+		if creditor.dues > 0 {
+			creditors = append(creditors, creditor)
+		}
+	}
+	if strategy == model.OLDEST_FIRST {
+		firstShared := make(map[string]int, len(creditors))
+		for _, creditor := range creditors {
+			firstShared[creditor.name] = t.firstSharedTransaction(member, creditor.name)
+		}
+		sort.SliceStable(creditors, func(i, j int) bool {
+			return firstShared[creditors[i].name] < firstShared[creditors[j].name]
+		})
+	}
+	return creditors
+}
+
+// firstSharedTransaction returns the ID of the earliest transaction in which the creditor
+// paid for something the member received.
+func (t *TrackerServiceImpl) firstSharedTransaction(member, creditor string) int {
+	for _, transaction := range t.storage.GetHistory() {
+		if transaction.Payers[creditor] > 0 && transaction.Shares[member] > 0 {
+			return transaction.ID
+		}
+	}
+	return math.MaxInt32
+}
+
+// allocatePayment decides how much of the amount goes to each creditor without paying
+// anyone more than they are owed.
+func (t *TrackerServiceImpl) allocatePayment(creditors []Member, amount int64, strategy model.AllocationStrategy) []Member {
+	payments := make([]Member, len(creditors))
+	var total int64
+	for i, creditor := range creditors {
+		payments[i].name = creditor.name
+		total += creditor.dues
+	}
+
+	if strategy == model.PROPORTIONAL && amount < total {
+		remaining := amount
+		for i, creditor := range creditors {
+			payments[i].dues = amount * creditor.dues / total
+			remaining -= payments[i].dues
+		}
+		for i := 0; remaining > 0; i++ {
+			payments[i].dues++
+			remaining--
+		}
+		return payments
+	}
+
+	remaining := amount
+	for i, creditor := range creditors {
+		payments[i].dues = creditor.dues
+		if remaining < creditor.dues {
+			payments[i].dues = remaining
+		}
+		remaining -= payments[i].dues
+	}
+	return payments
+}
+
+// SettleMember records the payments that bring a member's balance to zero, one CLEAR_DUE
+// per outstanding simplified due, and returns them as "<payer> PAID <receiver> <amount>".
+// Any security deposit the member paid is released first so it offsets their dues.
+func (t *TrackerServiceImpl) SettleMember(member string) ([]string, error) {
+	if err := t.validateHousemateExists(member); err != nil {
+		return nil, err
+	}
+	result, err := t.releaseDeposit(member)
+	if err != nil {
+		return nil, err
+	}
+
+	var outstanding []model.Due
+	for from, dues := range t.storage.GetTransactions() {
+		for to, due := range dues {
+			if due > 0 && (from == member || to == member) {
+				outstanding = append(outstanding, model.Due{From: from, To: to, Amount: due})
+			}
+		}
+	}
+	sort.Slice(outstanding, func(i, j int) bool {
+		if outstanding[i].From != outstanding[j].From {
+			return outstanding[i].From < outstanding[j].From
+		}
+		return outstanding[i].To < outstanding[j].To
+	})
+
+	for _, due := range outstanding {
+		if _, err := t.postTransaction(model.Transaction{
+			Type:   model.PAYMENT_TRANSACTION,
+			Amount: due.Amount,
+			Payers: map[string]int64{due.From: due.Amount},
+			Shares: map[string]int64{due.To: due.Amount},
+		}); err != nil {
+			return nil, err
+		}
+		result = append(result, fmt.Sprintf("%s PAID %s %d", due.From, due.To, due.Amount))
+	}
+	return result, nil
+}
+
+// TransferDues hands everything a member owes and is owed over to another housemate,
+// leaving the member with a zero balance. Any security deposit the member paid is
+// released first and handed over along with the rest.
+func (t *TrackerServiceImpl) TransferDues(member, receiver string) ([]string, error) {
+	if err := t.validateHousemateExists(member); err != nil {
+		return nil, err
+	}
+	if err := t.validateHousemateExists(receiver); err != nil {
+		return nil, err
+	}
+	if member == receiver {
+		return nil, errors.New(string(model.INVALID_TRANSFER))
+	}
+	result, err := t.releaseDeposit(member)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := t.storage.GetInAmount(member) - t.storage.GetOutAmount(member)
+	transaction := model.Transaction{
+		Type:   model.TRANSFER_TRANSACTION,
+		Amount: balance,
+		Payers: map[string]int64{receiver: balance},
+		Shares: map[string]int64{member: balance},
+	}
+	if balance < 0 {
+		transaction.Amount = -balance
+		transaction.Payers = map[string]int64{member: -balance}
+		transaction.Shares = map[string]int64{receiver: -balance}
+	}
+	if _, err := t.postTransaction(transaction); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// releaseDeposit turns the security deposit a member paid into a due owed to them by its
+// holder, so that it offsets whatever the member still owes. It returns the release as
+// "<holder> RELEASED DEPOSIT OF <member> <amount>", or nothing when there is no deposit.
+func (t *TrackerServiceImpl) releaseDeposit(member string) ([]string, error) {
+	deposit, ok := t.storage.GetDeposit(member)
+	if !ok {
+		return nil, nil
+	}
+	if _, err := t.postTransaction(model.Transaction{
+		Type:   model.DEPOSIT_RELEASE,
+		Amount: deposit.Amount,
+		Payers: map[string]int64{member: deposit.Amount},
+		Shares: map[string]int64{deposit.Holder: deposit.Amount},
+	}); err != nil {
+		return nil, err
+	}
+	t.storage.RemoveDeposit(member)
+	return []string{fmt.Sprintf("%s RELEASED DEPOSIT OF %s %d", deposit.Holder, member, deposit.Amount)}, nil
+}
@@ -0,0 +1,76 @@
+package expense
+
+import (
+	"errors"
+	"splitwise/model"
+	"time"
+)
+
+// SpendPeriod adds an expense covering the days from..to, inclusive. Each beneficiary's share
+// is weighted by the days in that period they lived in the house and were not away.
+func (t *TrackerServiceImpl) SpendPeriod(amount int64, payer string, from, to time.Time, beneficiaries []string) (string, error) {
+	if err := validateAmount(amount); err != nil {
+		return "", err
+	}
+	if to.Before(from) {
+		return "", errors.New(string(model.INVALID_PERIOD))
+	}
+
+	days := make(map[string]int64, len(beneficiaries))
+	var totalDays int64
+	for _, beneficiary := range beneficiaries {
+		if err := t.validateHousemateExists(beneficiary); err != nil {
+			return "", err
+		}
+		if _, counted := days[beneficiary]; counted {
+			continue
+		}
+		days[beneficiary] = t.daysPresent(beneficiary, from, to)
+		totalDays += days[beneficiary]
+	}
+	if totalDays == 0 {
+		return "", errors.New(string(model.INVALID_PERIOD))
+	}
+
+	shares := make(map[string]int64, len(days))
+	for beneficiary, share := range apportion(amount, days, totalDays) {
+		if share > 0 {
+			shares[beneficiary] = share
+		}
+	}
+	return t.postTransaction(model.Transaction{
+		Type:   model.PERIOD_TRANSACTION,
+		Amount: amount,
+		Payers: map[string]int64{payer: amount},
+		Shares: shares,
+	})
+}
+
+// daysPresent counts the days from..to, inclusive, on which the member lived in the house
+// and was not away. A stay ends the day before the move out; an absence includes both ends.
+func (t *TrackerServiceImpl) daysPresent(member string, from, to time.Time) int64 {
+	stays := t.storage.GetStays(member)
+	aways := t.storage.GetAways(member)
+	var days int64
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if coversDay(stays, day, false) && !coversDay(aways, day, true) {
+			days++
+		}
+	}
+	return days
+}
+
+// coversDay reports whether any interval contains the day. An interval's To day is
+// only part of it when inclusiveEnd is set.
+func coversDay(intervals []model.Interval, day time.Time, inclusiveEnd bool) bool {
+	for _, interval := range intervals {
+		if !interval.From.IsZero() && day.Before(interval.From) {
+			continue
+		}
+		if !interval.To.IsZero() && (day.After(interval.To) || (!inclusiveEnd && day.Equal(interval.To))) {
+			continue
+		}
+		return true
+	}
+	return false
+}
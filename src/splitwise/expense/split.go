@@ -0,0 +1,48 @@
+package expense
+
+import (
+	"errors"
+	"splitwise/model"
+)
+
+// DefineSplit stores a named profile of weights used to share later expenses, such as rent
+// split by room size. Every housemate in the profile must live in the house.
+func (t *TrackerServiceImpl) DefineSplit(name string, weights map[string]int64) (string, error) {
+	if len(weights) == 0 {
+		return "", errors.New(string(model.INVALID_SPLIT))
+	}
+	for housemate, weight := range weights {
+		if err := t.validateHousemateExists(housemate); err != nil {
+			return "", err
+		}
+		if weight <= 0 {
+			return "", errors.New(string(model.INVALID_SPLIT))
+		}
+	}
+	t.storage.SetSplit(name, weights)
+	return string(model.SUCCESS), nil
+}
+
+// AddWeightedExpense adds an expense paid by the payer and shared according to a split profile.
+// The weights that were applied are kept with the expense.
+func (t *TrackerServiceImpl) AddWeightedExpense(amount int64, payer, split string) (string, error) {
+	if err := validateAmount(amount); err != nil {
+		return "", err
+	}
+	weights, ok := t.storage.GetSplit(split)
+	if !ok {
+		return "", errors.New(string(model.SPLIT_NOT_FOUND))
+	}
+	var totalWeight int64
+	for _, weight := range weights {
+		totalWeight += weight
+	}
+	return t.postTransaction(model.Transaction{
+		Type:    model.EXPENSE_TRANSACTION,
+		Amount:  amount,
+		Payers:  map[string]int64{payer: amount},
+		Shares:  apportion(amount, weights, totalWeight),
+		Split:   split,
+		Weights: weights,
+	})
+}
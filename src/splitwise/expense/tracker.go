@@ -7,6 +7,7 @@ import (
 	"sort"
 	"splitwise/global"
 	"splitwise/model"
+	"strings"
 )
 
 type TrackerServiceImpl struct {
@@ -37,27 +38,168 @@ func (t *TrackerServiceImpl) AddExpense(amount float64, beneficiaries []string)
 		return "", err
 	}
 
-	if err := t.updateDuesForBeneficiaries(payer, beneficiaries[1:], amountPerPerson); err != nil {
+	return t.postTransaction(t.expenseTransaction(int64(math.Round(amount)), payer, beneficiaries[1:], amountPerPerson))
+}
+
+// expenseTransaction builds the history entry for a SPEND. The payer's share absorbs
+// any rounding so that the recorded shares match the dues that were added.
+func (t *TrackerServiceImpl) expenseTransaction(amount int64, payer string, beneficiaries []string, amountPerPerson int64) model.Transaction {
+	shares := make(map[string]int64)
+	shares[payer] = amount
+	for _, beneficiary := range beneficiaries {
+		shares[beneficiary] += amountPerPerson
+		shares[payer] -= amountPerPerson
+	}
+	return model.Transaction{
+		Type:   model.EXPENSE_TRANSACTION,
+		Amount: amount,
+		Payers: map[string]int64{payer: amount},
+		Shares: shares,
+	}
+}
+
+// AddMultiPayerExpense adds an expense paid jointly by several housemates and split evenly among the beneficiaries.
+func (t *TrackerServiceImpl) AddMultiPayerExpense(amount int64, payers map[string]int64, beneficiaries []string) (string, error) {
+	if err := validateAmount(amount); err != nil {
 		return "", err
 	}
+	var paid int64
+	for _, contribution := range payers {
+		if err := validateAmount(contribution); err != nil {
+			return "", err
+		}
+		paid += contribution
+	}
+	if paid != amount {
+		return "", errors.New(string(model.AMOUNT_MISMATCH))
+	}
 
-	t.storage.SimplifyDebt()
-	return string(model.SUCCESS), nil
+	return t.postTransaction(model.Transaction{
+		Type:   model.MULTI_EXPENSE_TRANSACTION,
+		Amount: amount,
+		Payers: payers,
+		Shares: splitAmongBeneficiaries(amount, beneficiaries),
+	})
 }
 
-func (t *TrackerServiceImpl) validateHousemateExists(housemate string) error {
-	if !t.storage.CheckHousemateExists(housemate) {
-		return errors.New(string(model.MEMBER_NOT_FOUND))
+// Lend records money handed directly from the lender to the borrower, without any split.
+func (t *TrackerServiceImpl) Lend(amount int64, lender, borrower string) (string, error) {
+	if err := validateAmount(amount); err != nil {
+		return "", err
 	}
-	return nil
+	return t.postTransaction(model.Transaction{
+		Type:   model.LOAN_TRANSACTION,
+		Amount: amount,
+		Payers: map[string]int64{lender: amount},
+		Shares: map[string]int64{borrower: amount},
+	})
 }
 
-func (t *TrackerServiceImpl) updateDuesForBeneficiaries(payer string, beneficiaries []string, amountPerPerson int64) error {
-	for _, beneficiary := range beneficiaries {
+// PayFor records an expense paid on behalf of the beneficiaries; the payer is not part of the split.
+func (t *TrackerServiceImpl) PayFor(amount int64, payer string, beneficiaries []string) (string, error) {
+	if err := validateAmount(amount); err != nil {
+		return "", err
+	}
+	return t.postTransaction(model.Transaction{
+		Type:   model.PAID_FOR_TRANSACTION,
+		Amount: amount,
+		Payers: map[string]int64{payer: amount},
+		Shares: splitAmongBeneficiaries(amount, beneficiaries),
+	})
+}
+
+// Refund records money returned to the receiver that reduces the shares of the beneficiaries.
+func (t *TrackerServiceImpl) Refund(amount int64, receiver string, beneficiaries []string) (string, error) {
+	if err := validateAmount(amount); err != nil {
+		return "", err
+	}
+	return t.postTransaction(model.Transaction{
+		Type:   model.REFUND_TRANSACTION,
+		Amount: amount,
+		Payers: splitAmongBeneficiaries(amount, beneficiaries),
+		Shares: map[string]int64{receiver: amount},
+	})
+}
+
+// RefundExpense fully reverses a previously recorded expense.
+func (t *TrackerServiceImpl) RefundExpense(id int) (string, error) {
+	original, ok := t.storage.GetTransaction(id)
+	if !ok {
+		return "", errors.New(string(model.TRANSACTION_NOT_FOUND))
+	}
+	if !isRefundable(original) || t.isRefunded(id) {
+		return "", errors.New(string(model.INVALID_REFUND))
+	}
+	return t.postTransaction(model.Transaction{
+		Type:     model.REFUND_TRANSACTION,
+		Amount:   original.Amount,
+		Payers:   original.Shares,
+		Shares:   original.Payers,
+		RefundOf: id,
+	})
+}
+
+// isRefundable reports whether a transaction is an expense that can be reversed.
+func isRefundable(transaction model.Transaction) bool {
+	switch transaction.Type {
+	case model.EXPENSE_TRANSACTION, model.MULTI_EXPENSE_TRANSACTION, model.PAID_FOR_TRANSACTION, model.BILL_TRANSACTION,
+		model.PERIOD_TRANSACTION:
+		return true
+	}
+	return false
+}
+
+// isRefunded reports whether a refund has already been recorded against the transaction.
+func (t *TrackerServiceImpl) isRefunded(id int) bool {
+	for _, transaction := range t.storage.GetHistory() {
+		if transaction.RefundOf == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ShowHistory returns every recorded transaction in the order it happened.
+func (t *TrackerServiceImpl) ShowHistory() []string {
+	history := t.storage.GetHistory()
+	result := make([]string, 0, len(history))
+	for _, transaction := range history {
+		result = append(result, formatTransaction(transaction))
+	}
+	return result
+}
+
+// postTransaction checks that every member of a transaction lives in the house, then posts
+// it to the member accounts, which updates the dues and records it in the history.
+func (t *TrackerServiceImpl) postTransaction(transaction model.Transaction) (string, error) {
+	for payer := range transaction.Payers {
+		if err := t.validateHousemateExists(payer); err != nil {
+			return "", err
+		}
+	}
+	for beneficiary := range transaction.Shares {
 		if err := t.validateHousemateExists(beneficiary); err != nil {
-			return err
+			return "", err
 		}
-		t.storage.AddOrUpdateDue(payer, beneficiary, amountPerPerson)
+	}
+
+	if _, err := t.storage.PostTransaction(transaction); err != nil {
+		return "", err
+	}
+	return string(model.SUCCESS), nil
+}
+
+// validateAmount rejects an amount that is not positive.
+func validateAmount(amount int64) error {
+	if amount <= 0 {
+		return errors.New(string(model.INVALID_AMOUNT))
+	}
+	return nil
+}
+
+func (t *TrackerServiceImpl) validateHousemateExists(housemate string) error {
+	if !t.storage.CheckHousemateExists(housemate) {
+		return errors.New(string(model.MEMBER_NOT_FOUND))
 	}
 	return nil
 }
@@ -85,19 +227,34 @@ func (t *TrackerServiceImpl) formatResult(members []Member) []string {
 }
 
 // ClearDues clears a specified amount of dues between two housemates.
+// When the house accepts overpayments, any amount above the due is kept as a credit
+// for the payer and reported alongside the remaining due.
 func (t *TrackerServiceImpl) ClearDues(from, to string, amount int64) (string, error) {
+	if err := validateAmount(amount); err != nil {
+		return "", err
+	}
 	if !t.storage.CheckHousemateExists(from) || !t.storage.CheckHousemateExists(to) {
 		return "", errors.New(string(model.MEMBER_NOT_FOUND))
 	}
 
 	dues := t.storage.GetDue(from, to)
 
-	if amount > dues {
+	if amount > dues && t.storage.GetSetting(model.OVERPAYMENT) != model.OVERPAYMENT_CREDIT {
 		return "", errors.New(string(model.INCORRECT_PAYMENT))
 	}
 
-	t.storage.ClearDues(from, to, amount)
+	if _, err := t.postTransaction(model.Transaction{
+		Type:   model.PAYMENT_TRANSACTION,
+		Amount: amount,
+		Payers: map[string]int64{from: amount},
+		Shares: map[string]int64{to: amount},
+	}); err != nil {
+		return "", err
+	}
 
+	if amount > dues {
+		return fmt.Sprintf("%d CREDIT %d", model.ZERO_DUE, amount-dues), nil
+	}
 	return fmt.Sprintf("%d", dues-amount), nil
 }
 
@@ -106,6 +263,67 @@ func (t *TrackerServiceImpl) calculateDues(amount float64, beneficiaryCount int)
 	return int64(math.Round(amount / float64(beneficiaryCount)))
 }
 
+// splitEvenly divides an amount into count shares that add up exactly to the amount.
+// Any remainder is spread one unit at a time over the first shares.
+func splitEvenly(amount int64, count int) []int64 {
+	shares := make([]int64, count)
+	if count == 0 {
+		return shares
+	}
+	base, remainder := amount/int64(count), amount%int64(count)
+	for i := range shares {
+		shares[i] = base
+		if int64(i) < remainder {
+			shares[i]++
+		}
+	}
+	return shares
+}
+
+// splitAmongBeneficiaries divides an amount evenly among the beneficiaries.
+func splitAmongBeneficiaries(amount int64, beneficiaries []string) map[string]int64 {
+	shares := make(map[string]int64, len(beneficiaries))
+	for i, share := range splitEvenly(amount, len(beneficiaries)) {
+		shares[beneficiaries[i]] += share
+	}
+	return shares
+}
+
+// formatTransaction renders a transaction as "<id> <type> <amount> PAID <payer:amount...> FOR <member:share...>",
+// followed by "OF <id>" for refunds of a logged expense, "USING <split> <member:weight...>" for
+// weighted expenses and "ON <date>" once the house date is set. Bill items follow on indented lines.
+func formatTransaction(transaction model.Transaction) string {
+	out := fmt.Sprintf("%d %s %d PAID %s FOR %s", transaction.ID, transaction.Type, transaction.Amount,
+		formatMemberAmounts(transaction.Payers), formatMemberAmounts(transaction.Shares))
+	if transaction.RefundOf != 0 {
+		out += fmt.Sprintf(" OF %d", transaction.RefundOf)
+	}
+	if transaction.Split != "" {
+		out += fmt.Sprintf(" USING %s %s", transaction.Split, formatMemberAmounts(transaction.Weights))
+	}
+	if !transaction.Date.IsZero() {
+		out += " ON " + transaction.Date.Format(model.DateLayout)
+	}
+	for _, item := range transaction.Items {
+		out += fmt.Sprintf("\n  %s %d %s", item.Kind, item.Amount, formatMemberAmounts(item.Shares))
+	}
+	return out
+}
+
+// formatMemberAmounts renders a map of amounts as space separated <name>:<amount> pairs sorted by name.
+func formatMemberAmounts(amounts map[string]int64) string {
+	names := make([]string, 0, len(amounts))
+	for name := range amounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s:%d", name, amounts[name]))
+	}
+	return strings.Join(parts, " ")
+}
+
 // sortMembersByDues sorts housemates by their dues in descending order and by name in ascending order for ties.
 func (t *TrackerServiceImpl) sortMembersByDues(dues map[string]int64) []Member {
 	members := t.mapToMembers(dues)
@@ -131,3 +349,8 @@ func (t *TrackerServiceImpl) sortMembers(members []Member) {
 		return members[i].dues > members[j].dues
 	})
 }
+
+// Check verifies the invariants of the books and returns one diagnostic per violation.
+func (t *TrackerServiceImpl) Check() []string {
+	return t.storage.CheckInvariants()
+}
@@ -0,0 +1,146 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"splitwise/model"
+)
+
+// CSVKind names what an EXPORT CSV writes.
+type CSVKind string
+
+// Kinds of CSV export.
+const (
+	LEDGER   CSVKind = "ledger"
+	BALANCES CSVKind = "balances"
+	DUES     CSVKind = "dues"
+)
+
+// WriteLedgerCSV writes one row per transaction that changed the dues, such as SPEND and
+// CLEAR_DUE, with its id, date, type, amount, payers and beneficiaries. One column per member
+// follows, holding the transaction's effect on them: what they paid minus their share. Each
+// member column therefore adds up to the member's balance.
+func WriteLedgerCSV(w io.Writer, history []model.Transaction) error {
+	history = ledgerTransactions(history)
+	members := ledgerMembers(history)
+	writer := newCSVWriter(w)
+	header := append([]string{"id", "date", "type", "amount", "payers", "beneficiaries"}, members...)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, transaction := range history {
+		date := ""
+		if !transaction.Date.IsZero() {
+			date = transaction.Date.Format(model.DateLayout)
+		}
+		row := []string{
+			strconv.Itoa(transaction.ID),
+			date,
+			string(transaction.Type),
+			strconv.FormatInt(transaction.Amount, 10),
+			strings.Join(sortedNames(transaction.Payers), " "),
+			strings.Join(sortedNames(transaction.Shares), " "),
+		}
+		for _, member := range members {
+			effect := transaction.Payers[member] - transaction.Shares[member]
+			if effect == 0 {
+				row = append(row, "")
+				continue
+			}
+			row = append(row, strconv.FormatInt(effect, 10))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteBalancesCSV writes the net balance of every housemate, sorted by name. A positive
+// balance is owed to the housemate.
+func WriteBalancesCSV(w io.Writer, balances map[string]int64) error {
+	writer := newCSVWriter(w)
+	if err := writer.Write([]string{"member", "balance"}); err != nil {
+		return err
+	}
+	for _, member := range sortedNames(balances) {
+		if err := writer.Write([]string{member, strconv.FormatInt(balances[member], 10)}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteDuesCSV writes one row per outstanding simplified due, keyed debtor -> creditor,
+// sorted by debtor and then creditor.
+func WriteDuesCSV(w io.Writer, dues map[string]map[string]int64) error {
+	writer := newCSVWriter(w)
+	if err := writer.Write([]string{"from", "to", "amount"}); err != nil {
+		return err
+	}
+	debtors := make([]string, 0, len(dues))
+	for from := range dues {
+		debtors = append(debtors, from)
+	}
+	sort.Strings(debtors)
+	for _, from := range debtors {
+		for _, to := range sortedNames(dues[from]) {
+			if dues[from][to] <= model.ZERO_DUE {
+				continue
+			}
+			if err := writer.Write([]string{from, to, strconv.FormatInt(dues[from][to], 10)}); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// newCSVWriter returns a writer ending records with CRLF, as RFC 4180 requires.
+func newCSVWriter(w io.Writer) *csv.Writer {
+	writer := csv.NewWriter(w)
+	writer.UseCRLF = true
+	return writer
+}
+
+// ledgerTransactions returns the transactions that changed the dues, in order.
+func ledgerTransactions(history []model.Transaction) []model.Transaction {
+	var ledger []model.Transaction
+	for _, transaction := range history {
+		if transaction.Type.AffectsDues() {
+			ledger = append(ledger, transaction)
+		}
+	}
+	return ledger
+}
+
+// ledgerMembers returns the sorted names of everyone who paid or shared a transaction.
+func ledgerMembers(history []model.Transaction) []string {
+	seen := make(map[string]int64)
+	for _, transaction := range history {
+		for member := range transaction.Payers {
+			seen[member]++
+		}
+		for member := range transaction.Shares {
+			seen[member]++
+		}
+	}
+	return sortedNames(seen)
+}
+
+// sortedNames returns the names in a map of amounts sorted by name.
+func sortedNames(values map[string]int64) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,74 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"splitwise/model"
+)
+
+func TestWriteLedgerCSV(t *testing.T) {
+	history := []model.Transaction{
+		{
+			ID:     1,
+			Date:   time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC),
+			Type:   model.EXPENSE_TRANSACTION,
+			Amount: 300,
+			Payers: map[string]int64{"ANDY": 300},
+			Shares: map[string]int64{"ANDY": 150, `O"NEIL,JR`: 150},
+		},
+		{
+			ID:     2,
+			Type:   model.KITTY_TOPUP_TRANSACTION,
+			Amount: 100,
+			Payers: map[string]int64{"ANDY": 100},
+			Shares: map[string]int64{model.KITTY: 100},
+		},
+		{
+			ID:     3,
+			Type:   model.PAYMENT_TRANSACTION,
+			Amount: 150,
+			Payers: map[string]int64{`O"NEIL,JR`: 150},
+			Shares: map[string]int64{"ANDY": 150},
+		},
+	}
+
+	var out bytes.Buffer
+	if err := WriteLedgerCSV(&out, history); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := "id,date,type,amount,payers,beneficiaries,ANDY,\"O\"\"NEIL,JR\"\r\n" +
+		"1,2026-01-10,SPEND,300,ANDY,\"ANDY O\"\"NEIL,JR\",150,-150\r\n" +
+		"3,,CLEAR_DUE,150,\"O\"\"NEIL,JR\",ANDY,-150,150\r\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestWriteBalancesCSV(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteBalancesCSV(&out, map[string]int64{"WOODY": -200, "ANDY": 200}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := "member,balance\r\nANDY,200\r\nWOODY,-200\r\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestWriteDuesCSV(t *testing.T) {
+	dues := map[string]map[string]int64{
+		"WOODY": {"ANDY": 200, "BUZZ": 0},
+		"BUZZ":  {"ANDY": 100},
+	}
+
+	var out bytes.Buffer
+	if err := WriteDuesCSV(&out, dues); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := "from,to,amount\r\nBUZZ,ANDY,100\r\nWOODY,ANDY,200\r\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,94 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"splitwise/model"
+)
+
+// GraphFormat names a text format for drawing the debt graph.
+type GraphFormat string
+
+// Graph formats.
+const (
+	DOT     GraphFormat = "dot"
+	MERMAID GraphFormat = "mermaid"
+)
+
+// WriteGraph renders the dues, keyed debtor -> creditor, as a directed graph. Every member
+// is a node labelled with their net balance and every positive due is an edge from the
+// debtor to the creditor weighted by the amount. Nodes and edges are sorted by name, so
+// the same dues always render the same text.
+func WriteGraph(w io.Writer, dues map[string]map[string]int64, balances map[string]int64, format GraphFormat) error {
+	if format != DOT && format != MERMAID {
+		return fmt.Errorf("unknown graph format %s", format)
+	}
+	nodes := make(map[string]int64, len(balances))
+	for member, balance := range balances {
+		nodes[member] = balance
+	}
+	var edges []model.Due
+	for from, row := range dues {
+		for to, amount := range row {
+			if amount <= model.ZERO_DUE {
+				continue
+			}
+			edges = append(edges, model.Due{From: from, To: to, Amount: amount})
+			for _, member := range []string{from, to} {
+				if _, ok := nodes[member]; !ok {
+					nodes[member] = model.ZERO_DUE
+				}
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	names := sortedNames(nodes)
+
+	var out strings.Builder
+	if format == DOT {
+		out.WriteString("digraph dues {\n")
+		for _, name := range names {
+			fmt.Fprintf(&out, "  %s [label=%s];\n", dotQuote(name), dotQuote(nodeLabel(name, nodes[name])))
+		}
+		for _, edge := range edges {
+			fmt.Fprintf(&out, "  %s -> %s [label=\"%d\", weight=%d];\n", dotQuote(edge.From), dotQuote(edge.To), edge.Amount, edge.Amount)
+		}
+		out.WriteString("}\n")
+	} else {
+		ids := make(map[string]string, len(names))
+		out.WriteString("graph LR\n")
+		for i, name := range names {
+			ids[name] = fmt.Sprintf("m%d", i)
+			fmt.Fprintf(&out, "  %s[\"%s\"]\n", ids[name], mermaidEscape(nodeLabel(name, nodes[name])))
+		}
+		for _, edge := range edges {
+			fmt.Fprintf(&out, "  %s -->|%d| %s\n", ids[edge.From], edge.Amount, ids[edge.To])
+		}
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// nodeLabel is the text shown on the node of a member.
+func nodeLabel(member string, balance int64) string {
+	return fmt.Sprintf("%s (%d)", member, balance)
+}
+
+// dotQuote quotes an identifier or label for DOT.
+func dotQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// mermaidEscape escapes a quoted Mermaid label. Mermaid has no backslash escapes, so the
+// characters that would end the label are written as entity codes.
+func mermaidEscape(value string) string {
+	return strings.NewReplacer(`"`, "#quot;").Replace(value)
+}
@@ -0,0 +1,59 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteGraph(t *testing.T) {
+	dues := map[string]map[string]int64{
+		"WOODY": {"ANDY": 150, "BO": 0},
+		`BO"Y`:  {"ANDY": 300},
+		"ANDY":  {"WOODY": 0},
+	}
+	balances := map[string]int64{"ANDY": 450, "WOODY": -150, `BO"Y`: -300, "REX": 0}
+
+	tests := []struct {
+		name     string
+		format   GraphFormat
+		expected string
+	}{
+		// TEST CASE 1: DOT quotes every name and omits zero edges
+		{"dot", DOT, "digraph dues {\n" +
+			"  \"ANDY\" [label=\"ANDY (450)\"];\n" +
+			"  \"BO\\\"Y\" [label=\"BO\\\"Y (-300)\"];\n" +
+			"  \"REX\" [label=\"REX (0)\"];\n" +
+			"  \"WOODY\" [label=\"WOODY (-150)\"];\n" +
+			"  \"BO\\\"Y\" -> \"ANDY\" [label=\"300\", weight=300];\n" +
+			"  \"WOODY\" -> \"ANDY\" [label=\"150\", weight=150];\n" +
+			"}\n"},
+		// TEST CASE 2: Mermaid uses generated node IDs and escapes labels
+		{"mermaid", MERMAID, "graph LR\n" +
+			"  m0[\"ANDY (450)\"]\n" +
+			"  m1[\"BO#quot;Y (-300)\"]\n" +
+			"  m2[\"REX (0)\"]\n" +
+			"  m3[\"WOODY (-150)\"]\n" +
+			"  m1 -->|300| m0\n" +
+			"  m3 -->|150| m0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 3; i++ {
+				var out bytes.Buffer
+				if err := WriteGraph(&out, dues, balances, tt.format); err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				if out.String() != tt.expected {
+					t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, out.String())
+				}
+			}
+		})
+	}
+
+	// TEST CASE 3: Unknown formats are rejected
+	var out bytes.Buffer
+	if err := WriteGraph(&out, dues, balances, GraphFormat("svg")); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,233 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"splitwise/model"
+)
+
+// LedgerFormat names a plain-text accounting format.
+type LedgerFormat string
+
+// Plain-text accounting formats.
+const (
+	BEANCOUNT  LedgerFormat = "beancount"
+	LEDGER_CLI LedgerFormat = "ledger"
+)
+
+const (
+	// DefaultCurrency is the commodity of exported amounts when none is given.
+	DefaultCurrency = "USD"
+
+	// housemateAccount holds what the house owes each housemate: the money they put in,
+	// less the money they received. It is negative while the house owes them.
+	housemateAccount = "Liabilities:Housemates"
+	// expenseAccount holds what each housemate consumed, per category.
+	expenseAccount = "Expenses"
+	// refundCategory is the category of refunds that do not reverse a known expense.
+	refundCategory = "Refunds"
+)
+
+// expenseCategories maps each type of expense to the category used when the expense has
+// none of its own. Transactions of other types move money between housemates only.
+var expenseCategories = map[model.TransactionType]string{
+	model.EXPENSE_TRANSACTION:       "Shared",
+	model.MULTI_EXPENSE_TRANSACTION: "Shared",
+	model.PAID_FOR_TRANSACTION:      "Shared",
+	model.BILL_TRANSACTION:          "Bills",
+	model.PERIOD_TRANSACTION:        "Recurring",
+}
+
+// invalidAccountCharacters matches what account components cannot contain in either format.
+var invalidAccountCharacters = regexp.MustCompile(`[^A-Za-z0-9-]`)
+
+// currencyPattern matches the commodity names both formats accept.
+var currencyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]{1,23}$`)
+
+// IsValidCurrency reports whether a commodity name can be used in either format.
+func IsValidCurrency(currency string) bool {
+	return currencyPattern.MatchString(currency)
+}
+
+// posting is one line of a double-entry transaction.
+type posting struct {
+	account string
+	amount  int64
+}
+
+// entry is a balanced double-entry transaction.
+type entry struct {
+	date     time.Time
+	payee    string
+	postings []posting
+}
+
+// WriteLedger renders every transaction that changed the dues as a balanced double-entry
+// transaction in the given format. The payers of an expense are credited on their account
+// under Liabilities:Housemates and the shares are debited to Expenses:<category>:<member>,
+// where the category is the expense's own or, failing that, comes from its type.
+// A refund does the opposite, and payments and loans move amounts between housemate
+// accounts only.
+func WriteLedger(w io.Writer, history []model.Transaction, format LedgerFormat, currency string) error {
+	if format != BEANCOUNT && format != LEDGER_CLI {
+		return fmt.Errorf("unknown ledger format %s", format)
+	}
+	if err := checkAccountNames(history); err != nil {
+		return err
+	}
+	entries := ledgerEntries(history)
+	opened := openingDate(entries)
+
+	var out strings.Builder
+	if format == BEANCOUNT {
+		fmt.Fprintf(&out, "option \"operating_currency\" \"%s\"\n\n", currency)
+		for _, account := range ledgerAccounts(entries) {
+			fmt.Fprintf(&out, "%s open %s %s\n", opened.Format(model.DateLayout), account, currency)
+		}
+	} else {
+		for _, account := range ledgerAccounts(entries) {
+			fmt.Fprintf(&out, "account %s\n", account)
+		}
+	}
+	for _, entry := range entries {
+		if entry.date.IsZero() {
+			entry.date = opened
+		}
+		out.WriteString("\n")
+		if format == BEANCOUNT {
+			fmt.Fprintf(&out, "%s * \"%s\"\n", entry.date.Format(model.DateLayout), entry.payee)
+		} else {
+			fmt.Fprintf(&out, "%s * %s\n", entry.date.Format("2006/01/02"), entry.payee)
+		}
+		for _, posting := range entry.postings {
+			fmt.Fprintf(&out, "  %-40s  %d %s\n", posting.account, posting.amount, currency)
+		}
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// ledgerEntries builds the double-entry transactions of the history. Undated transactions
+// take the date of the transaction before them, and stay undated if there is none.
+func ledgerEntries(history []model.Transaction) []entry {
+	categories := make(map[int]string)
+	var entries []entry
+	var date time.Time
+	for _, transaction := range history {
+		if !transaction.Type.AffectsDues() {
+			continue
+		}
+		if !transaction.Date.IsZero() {
+			date = transaction.Date
+		}
+		category, isExpense := expenseCategories[transaction.Type]
+		if isExpense && strings.TrimSpace(transaction.Category) != "" {
+			category = categoryName(transaction.Category)
+		}
+		categories[transaction.ID] = category
+
+		payersAccount, sharesAccount := housemateAccount, housemateAccount
+		switch {
+		case isExpense:
+			sharesAccount = expenseAccount + ":" + category
+		case transaction.Type == model.REFUND_TRANSACTION:
+			category = refundCategory
+			if original, ok := categories[transaction.RefundOf]; ok && original != "" {
+				category = original
+			}
+			payersAccount = expenseAccount + ":" + category
+		}
+
+		postings := make(map[string]int64)
+		for member, paid := range transaction.Payers {
+			postings[payersAccount+":"+accountName(member)] -= paid
+		}
+		for member, share := range transaction.Shares {
+			postings[sharesAccount+":"+accountName(member)] += share
+		}
+		entry := entry{date: date, payee: fmt.Sprintf("%s #%d", transaction.Type, transaction.ID)}
+		for _, account := range sortedNames(postings) {
+			if postings[account] != 0 {
+				entry.postings = append(entry.postings, posting{account: account, amount: postings[account]})
+			}
+		}
+		if len(entry.postings) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// checkAccountNames refuses a history in which two members get the same account name, as
+// their postings would otherwise be merged.
+func checkAccountNames(history []model.Transaction) error {
+	owners := make(map[string]string)
+	for _, transaction := range history {
+		if !transaction.Type.AffectsDues() {
+			continue
+		}
+		for _, members := range []map[string]int64{transaction.Payers, transaction.Shares} {
+			for member := range members {
+				name := accountName(member)
+				if owner, ok := owners[name]; ok && owner != member {
+					first, second := owner, member
+					if second < first {
+						first, second = second, first
+					}
+					return fmt.Errorf("members %s and %s have the same account name %s", first, second, name)
+				}
+				owners[name] = member
+			}
+		}
+	}
+	return nil
+}
+
+// ledgerAccounts returns every account the entries post to, sorted.
+func ledgerAccounts(entries []entry) []string {
+	accounts := make(map[string]int64)
+	for _, entry := range entries {
+		for _, posting := range entry.postings {
+			accounts[posting.account]++
+		}
+	}
+	return sortedNames(accounts)
+}
+
+// openingDate returns the date accounts are opened on: the first date of the entries, or
+// the Unix epoch if none is dated.
+func openingDate(entries []entry) time.Time {
+	var opened time.Time
+	for _, entry := range entries {
+		if !entry.date.IsZero() && (opened.IsZero() || entry.date.Before(opened)) {
+			opened = entry.date
+		}
+	}
+	if opened.IsZero() {
+		return time.Unix(0, 0).UTC()
+	}
+	return opened
+}
+
+// categoryName turns a category into an account component, with dashes for spaces and a
+// capital first letter.
+func categoryName(category string) string {
+	name := []rune(strings.Join(strings.Fields(category), "-"))
+	name[0] = unicode.ToUpper(name[0])
+	return accountName(string(name))
+}
+
+// accountName turns a member name into an account component both formats accept: letters,
+// digits and dashes, starting with a capital letter.
+func accountName(member string) string {
+	name := invalidAccountCharacters.ReplaceAllString(member, "-")
+	if name == "" || !(name[0] >= 'A' && name[0] <= 'Z') {
+		name = "M" + name
+	}
+	return name
+}
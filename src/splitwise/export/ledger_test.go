@@ -0,0 +1,158 @@
+package export
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"splitwise/model"
+)
+
+// ledgerHistory returns a history with an expense, a payment, a refund of the expense and
+// a kitty top-up, which does not change the dues.
+func ledgerHistory() []model.Transaction {
+	return []model.Transaction{
+		{
+			ID:     1,
+			Date:   time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+			Type:   model.BILL_TRANSACTION,
+			Amount: 300,
+			Payers: map[string]int64{"ANDY": 300},
+			Shares: map[string]int64{"ANDY": 150, "O_NEIL": 150},
+		},
+		{
+			ID:     2,
+			Type:   model.PAYMENT_TRANSACTION,
+			Amount: 100,
+			Payers: map[string]int64{"O_NEIL": 100},
+			Shares: map[string]int64{"ANDY": 100},
+		},
+		{
+			ID:     3,
+			Date:   time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC),
+			Type:   model.KITTY_TOPUP_TRANSACTION,
+			Amount: 50,
+			Payers: map[string]int64{"ANDY": 50},
+			Shares: map[string]int64{model.KITTY: 50},
+		},
+		{
+			ID:       4,
+			Date:     time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC),
+			Type:     model.REFUND_TRANSACTION,
+			Amount:   300,
+			Payers:   map[string]int64{"ANDY": 150, "O_NEIL": 150},
+			Shares:   map[string]int64{"ANDY": 300},
+			RefundOf: 1,
+		},
+	}
+}
+
+func TestWriteLedgerBalances(t *testing.T) {
+	postingLine := regexp.MustCompile(`^  ([A-Z][A-Za-z0-9-]*(?::[A-Z][A-Za-z0-9-]*)+) +(-?\d+) EUR$`)
+	headers := map[LedgerFormat]*regexp.Regexp{
+		BEANCOUNT:  regexp.MustCompile(`^\d{4}-\d{2}-\d{2} \* "[^"]+"$`),
+		LEDGER_CLI: regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \* \S.*$`),
+	}
+	declarations := map[LedgerFormat]*regexp.Regexp{
+		BEANCOUNT:  regexp.MustCompile(`^(option "operating_currency" "EUR"|2026-01-02 open [A-Za-z0-9:-]+ EUR)$`),
+		LEDGER_CLI: regexp.MustCompile(`^account [A-Za-z0-9:-]+$`),
+	}
+
+	for format, header := range headers {
+		var out bytes.Buffer
+		if err := WriteLedger(&out, ledgerHistory(), format, "EUR"); err != nil {
+			t.Fatalf("%s: expected no error, got %v", format, err)
+		}
+
+		// TEST CASE 1: Every line follows the format and every entry balances
+		var entries int
+		var sum int64
+		inEntry := false
+		for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+			switch {
+			case line == "":
+				if inEntry && sum != 0 {
+					t.Errorf("%s: expected a balanced entry, got a sum of %d", format, sum)
+				}
+				inEntry, sum = false, 0
+			case header.MatchString(line):
+				inEntry = true
+				entries++
+			case inEntry && postingLine.MatchString(line):
+				amount, _ := strconv.ParseInt(postingLine.FindStringSubmatch(line)[2], 10, 64)
+				sum += amount
+			case !inEntry && declarations[format].MatchString(line):
+			default:
+				t.Errorf("%s: unexpected line %q", format, line)
+			}
+		}
+		if sum != 0 {
+			t.Errorf("%s: expected a balanced entry, got a sum of %d", format, sum)
+		}
+
+		// TEST CASE 2: The kitty top-up is left out
+		if entries != 3 {
+			t.Errorf("%s: expected 3 entries, got %d", format, entries)
+		}
+	}
+}
+
+func TestWriteLedgerAccounts(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteLedger(&out, ledgerHistory(), BEANCOUNT, DefaultCurrency); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := []string{
+		// The bill's shares are expenses and the payer is owed the cost
+		"  Expenses:Bills:O-NEIL                     150 USD",
+		"  Liabilities:Housemates:ANDY               -300 USD",
+		// The undated payment takes the date of the bill
+		"2026-01-02 * \"CLEAR_DUE #2\"",
+		// The refund reverses the bill in the same category
+		"  Expenses:Bills:O-NEIL                     -150 USD",
+	}
+	for _, line := range expected {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("Expected the line %q in\n%s", line, out.String())
+		}
+	}
+
+	// TEST CASE 2: An expense with its own category is booked under it
+	out.Reset()
+	history := []model.Transaction{
+		{ID: 1, Type: model.EXPENSE_TRANSACTION, Amount: 40, Category: "dining out",
+			Payers: map[string]int64{"ANDY": 40}, Shares: map[string]int64{"ANDY": 20, "O_NEIL": 20}},
+		{ID: 2, Type: model.REFUND_TRANSACTION, Amount: 20, RefundOf: 1,
+			Payers: map[string]int64{"O_NEIL": 20}, Shares: map[string]int64{"ANDY": 20}},
+	}
+	if err := WriteLedger(&out, history, BEANCOUNT, DefaultCurrency); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, line := range []string{
+		"  Expenses:Dining-out:O-NEIL                20 USD",
+		"  Expenses:Dining-out:O-NEIL                -20 USD",
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("Expected the line %q in\n%s", line, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "Expenses:Shared") {
+		t.Errorf("Expected no Shared account in\n%s", out.String())
+	}
+
+	// TEST CASE 3: Members whose account names would be the same are refused
+	history = []model.Transaction{{ID: 1, Type: model.LOAN_TRANSACTION, Amount: 10,
+		Payers: map[string]int64{"O_NEIL": 10}, Shares: map[string]int64{"O-NEIL": 10}}}
+	if err := WriteLedger(&out, history, BEANCOUNT, DefaultCurrency); err == nil ||
+		err.Error() != "members O-NEIL and O_NEIL have the same account name O-NEIL" {
+		t.Errorf("Expected the account name collision, got %v", err)
+	}
+
+	// TEST CASE 4: Unknown formats are refused
+	if err := WriteLedger(&out, nil, "gnucash", DefaultCurrency); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
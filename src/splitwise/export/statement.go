@@ -0,0 +1,259 @@
+package export
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"splitwise/model"
+)
+
+// StatementFormat names a format for monthly statements.
+type StatementFormat string
+
+// Statement formats.
+const (
+	TEXT_STATEMENT     StatementFormat = "txt"
+	MARKDOWN_STATEMENT StatementFormat = "md"
+	HTML_STATEMENT     StatementFormat = "html"
+)
+
+// StatementLine is a transaction of the month that involved the member. Credit is what
+// raised their balance and Debit what lowered it, and Balance is their balance after it.
+type StatementLine struct {
+	ID      int
+	Date    time.Time
+	Type    model.TransactionType
+	Credit  int64
+	Debit   int64
+	Balance int64
+}
+
+// CounterpartyBalance is what a counterparty owes the member at the start and the end of
+// the month, negative when the member owes them.
+type CounterpartyBalance struct {
+	Member  string
+	Opening int64
+	Closing int64
+}
+
+// Statement is the activity of one member in one month. The closing balance is the
+// opening balance plus the expenses they paid, less their shares of expenses, plus the
+// payments they made, less the payments they received. Refunds count as negative
+// expenses. A positive balance is owed to the member.
+type Statement struct {
+	Member           string
+	Month            time.Time
+	Opening          int64
+	ExpensesPaid     int64
+	Shares           int64
+	PaymentsMade     int64
+	PaymentsReceived int64
+	Closing          int64
+	Lines            []StatementLine
+	Counterparties   []CounterpartyBalance
+}
+
+// BuildStatement builds the statement of a member for the month from the history. Only
+// transactions that changed the dues count. Undated transactions take the date of the
+// transaction before them and belong before every month if there is none. What each
+// counterparty owes is split out the same way the raw dues are.
+func BuildStatement(history []model.Transaction, member string, month time.Time) Statement {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	statement := Statement{Member: member, Month: start}
+	opening := make(map[string]int64)
+	closing := make(map[string]int64)
+
+	var date time.Time
+	for _, transaction := range ledgerTransactions(history) {
+		if !transaction.Date.IsZero() {
+			date = transaction.Date
+		}
+		if !date.Before(end) {
+			continue
+		}
+		counterparties := closing
+		inMonth := !date.IsZero() && !date.Before(start)
+		if !inMonth {
+			counterparties = opening
+		}
+		for _, due := range model.PairEffects(transaction.Effects()) {
+			if due.To == member {
+				counterparties[due.From] += due.Amount
+			} else if due.From == member {
+				counterparties[due.To] -= due.Amount
+			}
+		}
+
+		credit, debit := transaction.Payers[member], transaction.Shares[member]
+		if credit == model.ZERO_DUE && debit == model.ZERO_DUE {
+			continue
+		}
+		statement.Closing += credit - debit
+		if !inMonth {
+			statement.Opening += credit - debit
+			continue
+		}
+		switch _, isExpense := expenseCategories[transaction.Type]; {
+		case isExpense:
+			statement.ExpensesPaid += credit
+			statement.Shares += debit
+		case transaction.Type == model.REFUND_TRANSACTION:
+			statement.ExpensesPaid -= debit
+			statement.Shares -= credit
+		default:
+			statement.PaymentsMade += credit
+			statement.PaymentsReceived += debit
+		}
+		statement.Lines = append(statement.Lines, StatementLine{
+			ID:      transaction.ID,
+			Date:    transaction.Date,
+			Type:    transaction.Type,
+			Credit:  credit,
+			Debit:   debit,
+			Balance: statement.Closing,
+		})
+	}
+
+	for name, amount := range opening {
+		closing[name] += amount
+	}
+	for _, name := range sortedNames(closing) {
+		if opening[name] != model.ZERO_DUE || closing[name] != model.ZERO_DUE {
+			statement.Counterparties = append(statement.Counterparties, CounterpartyBalance{
+				Member:  name,
+				Opening: opening[name],
+				Closing: closing[name],
+			})
+		}
+	}
+	return statement
+}
+
+// table is a titled table of a statement.
+type table struct {
+	title  string
+	header []string
+	rows   [][]string
+}
+
+// WriteStatement renders the statement as plain text, Markdown or a standalone HTML page.
+// It lists the transactions of the month, then the summary, then the balance with every
+// counterparty.
+func WriteStatement(w io.Writer, statement Statement, format StatementFormat) error {
+	title := fmt.Sprintf("Statement for %s, %s", statement.Member, statement.Month.Format(model.MonthLayout))
+	tables := statementTables(statement)
+
+	var out strings.Builder
+	switch format {
+	case TEXT_STATEMENT:
+		out.WriteString(title + "\n")
+		for _, table := range tables {
+			fmt.Fprintf(&out, "\n%s\n", table.title)
+			writer := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+			for _, row := range append([][]string{table.header}, table.rows...) {
+				fmt.Fprintln(writer, strings.Join(row, "\t"))
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+		}
+	case MARKDOWN_STATEMENT:
+		fmt.Fprintf(&out, "# %s\n", markdownEscape(title))
+		for _, table := range tables {
+			fmt.Fprintf(&out, "\n## %s\n\n", table.title)
+			fmt.Fprintf(&out, "| %s |\n", strings.Join(table.header, " | "))
+			out.WriteString("|" + strings.Repeat(" --- |", len(table.header)) + "\n")
+			for _, row := range table.rows {
+				cells := make([]string, len(row))
+				for i, cell := range row {
+					cells[i] = markdownEscape(cell)
+				}
+				fmt.Fprintf(&out, "| %s |\n", strings.Join(cells, " | "))
+			}
+		}
+	case HTML_STATEMENT:
+		out.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+		fmt.Fprintf(&out, "<title>%s</title>\n</head>\n<body>\n<h1>%s</h1>\n", html.EscapeString(title), html.EscapeString(title))
+		for _, table := range tables {
+			fmt.Fprintf(&out, "<h2>%s</h2>\n<table>\n", html.EscapeString(table.title))
+			out.WriteString(htmlRow("th", table.header))
+			for _, row := range table.rows {
+				out.WriteString(htmlRow("td", row))
+			}
+			out.WriteString("</table>\n")
+		}
+		out.WriteString("</body>\n</html>\n")
+	default:
+		return fmt.Errorf("unknown statement format %s", format)
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// statementTables lays out the transactions, the summary and the counterparties.
+func statementTables(statement Statement) []table {
+	transactions := table{
+		title:  "Transactions",
+		header: []string{"Date", "ID", "Type", "Credit", "Debit", "Balance"},
+	}
+	for _, line := range statement.Lines {
+		date := "-"
+		if !line.Date.IsZero() {
+			date = line.Date.Format(model.DateLayout)
+		}
+		transactions.rows = append(transactions.rows, []string{
+			date, strconv.Itoa(line.ID), string(line.Type), formatAmount(line.Credit), formatAmount(line.Debit), formatAmount(line.Balance),
+		})
+	}
+
+	summary := table{
+		title:  "Summary",
+		header: []string{"Item", "Amount"},
+		rows: [][]string{
+			{"Opening balance", formatAmount(statement.Opening)},
+			{"Expenses paid", formatAmount(statement.ExpensesPaid)},
+			{"Share of expenses", formatAmount(statement.Shares)},
+			{"Payments made", formatAmount(statement.PaymentsMade)},
+			{"Payments received", formatAmount(statement.PaymentsReceived)},
+			{"Closing balance", formatAmount(statement.Closing)},
+		},
+	}
+
+	counterparties := table{
+		title:  "Counterparties",
+		header: []string{"Member", "Opening", "Closing"},
+	}
+	for _, counterparty := range statement.Counterparties {
+		counterparties.rows = append(counterparties.rows, []string{
+			counterparty.Member, formatAmount(counterparty.Opening), formatAmount(counterparty.Closing),
+		})
+	}
+	return []table{transactions, summary, counterparties}
+}
+
+// formatAmount renders an amount of the statement.
+func formatAmount(amount int64) string {
+	return strconv.FormatInt(amount, 10)
+}
+
+// markdownEscape escapes the characters that would break a Markdown table cell or heading.
+func markdownEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`).Replace(value)
+}
+
+// htmlRow renders one row of an HTML table with cells of the given tag.
+func htmlRow(tag string, cells []string) string {
+	var row strings.Builder
+	row.WriteString("<tr>")
+	for _, cell := range cells {
+		fmt.Fprintf(&row, "<%s>%s</%s>", tag, html.EscapeString(cell), tag)
+	}
+	row.WriteString("</tr>\n")
+	return row.String()
+}
@@ -0,0 +1,142 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"splitwise/model"
+)
+
+func statementHistory() []model.Transaction {
+	return []model.Transaction{
+		{
+			ID:     1,
+			Date:   time.Date(2026, 2, 20, 0, 0, 0, 0, time.UTC),
+			Type:   model.EXPENSE_TRANSACTION,
+			Amount: 900,
+			Payers: map[string]int64{"ANDY": 900},
+			Shares: map[string]int64{"ANDY": 300, "WOODY": 300, "BO": 300},
+		},
+		{
+			ID:     2,
+			Date:   time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC),
+			Type:   model.EXPENSE_TRANSACTION,
+			Amount: 600,
+			Payers: map[string]int64{"WOODY": 600},
+			Shares: map[string]int64{"ANDY": 300, "WOODY": 300},
+		},
+		{
+			ID:     3,
+			Type:   model.KITTY_TOPUP_TRANSACTION,
+			Amount: 100,
+			Payers: map[string]int64{"ANDY": 100},
+			Shares: map[string]int64{model.KITTY: 100},
+		},
+		{
+			ID:     4,
+			Type:   model.PAYMENT_TRANSACTION,
+			Amount: 100,
+			Payers: map[string]int64{"BO": 100},
+			Shares: map[string]int64{"ANDY": 100},
+		},
+		{
+			ID:     5,
+			Type:   model.REFUND_TRANSACTION,
+			Amount: 100,
+			Payers: map[string]int64{"ANDY": 50, "WOODY": 50},
+			Shares: map[string]int64{"ANDY": 100},
+		},
+		{
+			ID:     6,
+			Date:   time.Date(2026, 4, 2, 0, 0, 0, 0, time.UTC),
+			Type:   model.LOAN_TRANSACTION,
+			Amount: 50,
+			Payers: map[string]int64{"ANDY": 50},
+			Shares: map[string]int64{"WOODY": 50},
+		},
+	}
+}
+
+func TestBuildStatement(t *testing.T) {
+	statement := BuildStatement(statementHistory(), "ANDY", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	// TEST CASE 1: The summary reconciles from the opening to the closing balance
+	if statement.Opening != 600 || statement.ExpensesPaid != -100 || statement.Shares != 250 ||
+		statement.PaymentsMade != 0 || statement.PaymentsReceived != 100 || statement.Closing != 150 {
+		t.Errorf("Unexpected summary %+v", statement)
+	}
+	if statement.Opening+statement.ExpensesPaid-statement.Shares+statement.PaymentsMade-statement.PaymentsReceived != statement.Closing {
+		t.Errorf("Expected the summary to reconcile, got %+v", statement)
+	}
+
+	// TEST CASE 2: Undated transactions take the date before them and informational ones are left out
+	if len(statement.Lines) != 3 || statement.Lines[1].ID != 4 || statement.Lines[2].ID != 5 || statement.Lines[2].Balance != 150 {
+		t.Errorf("Expected lines for transactions 2, 4 and 5, got %+v", statement.Lines)
+	}
+
+	// TEST CASE 3: The counterparty balances add up to the closing balance
+	expected := []CounterpartyBalance{{"BO", 300, 200}, {"WOODY", 300, -50}}
+	if len(statement.Counterparties) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, statement.Counterparties)
+	}
+	var total int64
+	for i, counterparty := range statement.Counterparties {
+		if counterparty != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], counterparty)
+		}
+		total += counterparty.Closing
+	}
+	if total != statement.Closing {
+		t.Errorf("Expected the counterparties to sum to %d, got %d", statement.Closing, total)
+	}
+
+	// TEST CASE 4: A later month opens at the previous closing balance
+	april := BuildStatement(statementHistory(), "ANDY", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC))
+	if april.Opening != 150 || april.PaymentsMade != 50 || april.Closing != 200 {
+		t.Errorf("Unexpected April summary %+v", april)
+	}
+}
+
+func TestWriteStatement(t *testing.T) {
+	statement := BuildStatement(statementHistory(), "ANDY", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	// TEST CASE 1: Plain text lays out aligned columns
+	var out bytes.Buffer
+	if err := WriteStatement(&out, statement, TEXT_STATEMENT); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, line := range []string{
+		"Statement for ANDY, 2026-03\n",
+		"2026-03-05  2   SPEND      0       300    300\n",
+		"Closing balance    150\n",
+		"WOODY   300      -50\n",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Expected %q in:\n%s", line, out.String())
+		}
+	}
+
+	// TEST CASE 2: Markdown and HTML escape the cells
+	statement.Member = "<A|B>"
+	out.Reset()
+	if err := WriteStatement(&out, statement, MARKDOWN_STATEMENT); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "# Statement for <A\\|B>, 2026-03\n") || !strings.Contains(out.String(), "| CLEAR\\_DUE |") {
+		t.Errorf("Expected escaped Markdown, got:\n%s", out.String())
+	}
+	out.Reset()
+	if err := WriteStatement(&out, statement, HTML_STATEMENT); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "<h1>Statement for &lt;A|B&gt;, 2026-03</h1>") || !strings.HasSuffix(out.String(), "</html>\n") {
+		t.Errorf("Expected escaped HTML, got:\n%s", out.String())
+	}
+
+	// TEST CASE 3: Unknown formats are rejected
+	if err := WriteStatement(&out, statement, StatementFormat("pdf")); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,79 @@
+package global
+
+import (
+	"fmt"
+	"sort"
+	"splitwise/model"
+)
+
+// CheckInvariants verifies that the books of the house are consistent and returns one
+// diagnostic per violation, sorted, or nil when everything holds. It checks that:
+//   - no raw or simplified due is negative or owed by a housemate to themselves
+//   - every entry posted to the member accounts sums to zero
+//   - the member accounts sum to zero and former housemates hold no balance
+//   - the accounts, the raw dues and the simplified dues imply the same net positions
+func (g *GlobalMapStorage) CheckInvariants() []string {
+	var violations []string
+	violations = append(violations, checkEdges("dues", g.dues)...)
+	violations = append(violations, checkEdges("simplified_dues", g.simplifydues)...)
+
+	for index, entry := range g.entries {
+		if !entry.IsBalanced() {
+			violations = append(violations, fmt.Sprintf("UNBALANCED_ENTRY %d TRANSACTION %d", index+1, entry.TransactionID))
+		}
+	}
+
+	var total int64
+	for member, balance := range g.accounts {
+		total += balance
+		if g.former[member] && balance != model.ZERO_DUE {
+			violations = append(violations, fmt.Sprintf("FORMER_BALANCE %s %d", member, balance))
+		}
+	}
+	if total != model.ZERO_DUE {
+		violations = append(violations, fmt.Sprintf("NONZERO_SUM %d", total))
+	}
+
+	for housemate := range g.housemates {
+		account := g.accounts[housemate]
+		raw := g.GetInAmount(housemate) - g.GetOutAmount(housemate)
+		simplified := g.simplifiedNet(housemate)
+		if account != raw || account != simplified {
+			violations = append(violations, fmt.Sprintf("NET_MISMATCH %s ACCOUNT %d DUES %d SIMPLIFIED %d", housemate, account, raw, simplified))
+		}
+	}
+
+	sort.Strings(violations)
+	return violations
+}
+
+// simplifiedNet returns what the simplified dues owe the housemate minus what they owe
+func (g *GlobalMapStorage) simplifiedNet(housemate string) int64 {
+	var net int64
+	for debtor, dues := range g.simplifydues {
+		net += dues[housemate]
+		if debtor == housemate {
+			for _, due := range dues {
+				net -= due
+			}
+		}
+	}
+	return net
+}
+
+// checkEdges reports the negative edges of a dues map and the positive edges from a
+// housemate to themselves
+func checkEdges(name string, dues map[string]map[string]int64) []string {
+	var violations []string
+	for from, row := range dues {
+		for to, amount := range row {
+			if amount < model.ZERO_DUE {
+				violations = append(violations, fmt.Sprintf("NEGATIVE_EDGE %s %s %s %d", name, from, to, amount))
+			}
+			if from == to && amount != model.ZERO_DUE {
+				violations = append(violations, fmt.Sprintf("SELF_EDGE %s %s %d", name, from, amount))
+			}
+		}
+	}
+	return violations
+}
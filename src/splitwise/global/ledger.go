@@ -0,0 +1,76 @@
+package global
+
+import (
+	"errors"
+	"splitwise/model"
+)
+
+// PostTransaction records a transaction that changes the dues. Its effects are posted to
+// the member accounts as one balanced entry, the raw dues are projected from the postings,
+// the simplified dues from the new account balances, and the transaction is added to the
+// history. An entry that does not sum to zero, or a transaction dated in a closed period,
+// is rejected and nothing changes.
+func (g *GlobalMapStorage) PostTransaction(transaction model.Transaction) (model.Transaction, error) {
+	if err := g.CheckPeriodOpen(transaction.Date); err != nil {
+		return model.Transaction{}, err
+	}
+	effects := transaction.Effects()
+	if err := g.post(len(g.history)+1, effects); err != nil {
+		return model.Transaction{}, err
+	}
+	g.projectDues(effects)
+	g.SimplifyDebt()
+	return g.RecordTransaction(transaction)
+}
+
+// GetAccounts returns a copy of the balance of every member account, positive when the
+// member is owed money
+func (g *GlobalMapStorage) GetAccounts() map[string]int64 {
+	accounts := make(map[string]int64, len(g.accounts))
+	for member, balance := range g.accounts {
+		accounts[member] = balance
+	}
+	return accounts
+}
+
+// GetEntries returns a copy of every entry posted to the member accounts, in order
+func (g *GlobalMapStorage) GetEntries() []model.Entry {
+	entries := make([]model.Entry, len(g.entries))
+	copy(entries, g.entries)
+	return entries
+}
+
+// post appends a balanced entry for the effects and applies it to the member accounts
+func (g *GlobalMapStorage) post(transactionID int, effects map[string]int64) error {
+	entry := model.NewEntry(transactionID, effects)
+	if !entry.IsBalanced() {
+		return errors.New(string(model.UNBALANCED_POSTING))
+	}
+	if len(entry.Postings) == 0 {
+		return nil
+	}
+	for _, posting := range entry.Postings {
+		g.accounts[posting.Account] += posting.Amount
+	}
+	g.entries = append(g.entries, entry)
+	return nil
+}
+
+// projectDues adds the effects of an entry to the raw dues, pairing creditors with debtors
+// in name order
+func (g *GlobalMapStorage) projectDues(effects map[string]int64) {
+	for _, due := range model.PairEffects(effects) {
+		g.offsetEdge(due.To, due.From, due.Amount)
+	}
+}
+
+// balancesFromEntries sums the postings of the entries per account
+func balancesFromEntries(entries []model.Entry) map[string]int64 {
+	balances := make(map[string]int64)
+	for _, entry := range entries {
+		for _, posting := range entry.Postings {
+			balances[posting.Account] += posting.Amount
+		}
+	}
+	return balances
+}
@@ -0,0 +1,139 @@
+package global
+
+import (
+	"sort"
+	"splitwise/model"
+	"time"
+)
+
+// Snapshot is the complete state of a GlobalMapStorage in a form that can be serialized
+type Snapshot struct {
+	Housemates     []string                    `json:"housemates"`
+	Former         []string                    `json:"former"`
+	Dues           map[string]map[string]int64 `json:"dues"`
+	SimplifiedDues map[string]map[string]int64 `json:"simplified_dues"`
+	History        []model.Transaction         `json:"history"`
+	Settings       map[model.Setting]string    `json:"settings"`
+	Deposits       map[string]model.Deposit    `json:"deposits"`
+	Kitty          map[string]int64            `json:"kitty"`
+	Today          time.Time                   `json:"today"`
+	Stays          map[string][]model.Interval `json:"stays"`
+	Aways          map[string][]model.Interval `json:"aways"`
+	Splits         map[string]map[string]int64 `json:"splits"`
+	Imports        []string                    `json:"imports"`
+	Entries        []model.Entry               `json:"entries"`
+	ClosedPeriods  []model.ClosedPeriod        `json:"closed_periods"`
+	Audit          []model.AuditRecord         `json:"audit"`
+}
+
+// Snapshot captures the current state of the storage
+func (g *GlobalMapStorage) Snapshot() Snapshot {
+	housemates := g.GetHousemateNames()
+	sort.Strings(housemates)
+	return Snapshot{
+		Housemates:     housemates,
+		Former:         append([]string{}, g.GetFormerHousemateNames()...),
+		Dues:           copyNestedAmounts(g.dues),
+		SimplifiedDues: copyNestedAmounts(g.simplifydues),
+		History:        g.GetHistory(),
+		Settings:       copySettings(g.settings),
+		Deposits:       g.GetDeposits(),
+		Kitty:          g.GetKittyShares(),
+		Today:          g.today,
+		Stays:          copyIntervals(g.stays),
+		Aways:          copyIntervals(g.aways),
+		Splits:         copyNestedAmounts(g.splits),
+		Imports:        sortedKeys(g.imports),
+		Entries:        append([]model.Entry{}, g.entries...),
+		ClosedPeriods:  g.closedPeriods(),
+		Audit:          append([]model.AuditRecord{}, g.audit...),
+	}
+}
+
+// Restore replaces the state of the storage with the snapshot
+func (g *GlobalMapStorage) Restore(snapshot Snapshot) {
+	g.Reset()
+	for _, housemate := range snapshot.Housemates {
+		g.housemates[housemate] = true
+	}
+	for _, housemate := range snapshot.Former {
+		g.former[housemate] = true
+	}
+	g.dues = copyNestedAmounts(snapshot.Dues)
+	g.simplifydues = copyNestedAmounts(snapshot.SimplifiedDues)
+	g.history = append([]model.Transaction(nil), snapshot.History...)
+	for setting, value := range snapshot.Settings {
+		g.settings[setting] = value
+	}
+	for housemate, deposit := range snapshot.Deposits {
+		g.deposits[housemate] = deposit
+	}
+	for housemate, share := range snapshot.Kitty {
+		g.kitty[housemate] = share
+	}
+	g.today = snapshot.Today
+	g.stays = copyIntervals(snapshot.Stays)
+	g.aways = copyIntervals(snapshot.Aways)
+	g.splits = copyNestedAmounts(snapshot.Splits)
+	for _, fingerprint := range snapshot.Imports {
+		g.imports[fingerprint] = true
+	}
+	g.entries = append([]model.Entry(nil), snapshot.Entries...)
+	g.accounts = balancesFromEntries(snapshot.Entries)
+	for _, period := range snapshot.ClosedPeriods {
+		g.closed[period.Month] = period
+	}
+	g.audit = append([]model.AuditRecord(nil), snapshot.Audit...)
+}
+
+// closedPeriods returns the closed periods sorted by month, never returning nil
+func (g *GlobalMapStorage) closedPeriods() []model.ClosedPeriod {
+	periods := make([]model.ClosedPeriod, 0, len(g.closed))
+	for _, period := range g.closed {
+		periods = append(periods, period)
+	}
+	sort.Slice(periods, func(i, j int) bool {
+		return periods[i].Month < periods[j].Month
+	})
+	return periods
+}
+
+// copyNestedAmounts deep copies a map of maps of amounts, never returning nil
+func copyNestedAmounts(source map[string]map[string]int64) map[string]map[string]int64 {
+	copy := make(map[string]map[string]int64, len(source))
+	for outer, inner := range source {
+		copy[outer] = make(map[string]int64, len(inner))
+		for key, amount := range inner {
+			copy[outer][key] = amount
+		}
+	}
+	return copy
+}
+
+// copyIntervals deep copies a map of interval lists, never returning nil
+func copyIntervals(source map[string][]model.Interval) map[string][]model.Interval {
+	copy := make(map[string][]model.Interval, len(source))
+	for housemate, intervals := range source {
+		copy[housemate] = append([]model.Interval(nil), intervals...)
+	}
+	return copy
+}
+
+// sortedKeys returns the keys of a set in order, never returning nil
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// copySettings copies the settings map
+func copySettings(source map[model.Setting]string) map[model.Setting]string {
+	copy := make(map[model.Setting]string, len(source))
+	for setting, value := range source {
+		copy[setting] = value
+	}
+	return copy
+}
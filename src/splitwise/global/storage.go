@@ -1,30 +1,58 @@
 package global
 
 import (
+	"errors"
 	"math"
 	"sort"
 	"splitwise/model"
+	"time"
 )
 
 // GlobalMapStorage encapsulates all the housemates and their dues
 type GlobalMapStorage struct {
 	housemates   map[string]bool
+	former       map[string]bool
 	dues         map[string]map[string]int64
 	simplifydues map[string]map[string]int64
+	history      []model.Transaction
+	settings     map[model.Setting]string
+	deposits     map[string]model.Deposit
+	kitty        map[string]int64
+	today        time.Time
+	stays        map[string][]model.Interval
+	aways        map[string][]model.Interval
+	splits       map[string]map[string]int64
+	imports      map[string]bool
+	accounts     map[string]int64
+	entries      []model.Entry
+	closed       map[string]model.ClosedPeriod
+	audit        []model.AuditRecord
 }
 
 // NewGlobalMapStorage initializes a new GlobalMapStorage with empty maps
 func NewGlobalMapStorage() *GlobalMapStorage {
 	return &GlobalMapStorage{
 		housemates:   make(map[string]bool),
+		former:       make(map[string]bool),
 		dues:         make(map[string]map[string]int64),
 		simplifydues: make(map[string]map[string]int64),
+		settings:     model.DefaultSettings(),
+		deposits:     make(map[string]model.Deposit),
+		kitty:        make(map[string]int64),
+		stays:        make(map[string][]model.Interval),
+		aways:        make(map[string][]model.Interval),
+		splits:       make(map[string]map[string]int64),
+		imports:      make(map[string]bool),
+		accounts:     make(map[string]int64),
+		closed:       make(map[string]model.ClosedPeriod),
 	}
 }
 
 // AddHousemate adds a new housemate to the system
 func (g *GlobalMapStorage) AddHousemate(housemate string) {
 	g.housemates[housemate] = true
+	delete(g.former, housemate)
+	g.stays[housemate] = append(g.stays[housemate], model.Interval{From: g.today})
 	g.dues[housemate] = make(map[string]int64)
 	g.simplifydues[housemate] = make(map[string]int64)
 	for name := range g.housemates {
@@ -38,14 +66,57 @@ func (g *GlobalMapStorage) AddHousemate(housemate string) {
 	}
 }
 
-// RemoveHousemate removes a housemate and cleans up their dues
+// RemoveHousemate removes a housemate, cleans up their dues and archives them as a former housemate
 func (g *GlobalMapStorage) RemoveHousemate(housemate string) {
+	g.rerouteDues(housemate)
 	delete(g.housemates, housemate)
+	g.former[housemate] = true
+	if stays := g.stays[housemate]; len(stays) > 0 {
+		stays[len(stays)-1].To = g.today
+	}
 	g.cleanupDues(housemate)
 	delete(g.dues, housemate)
 	delete(g.simplifydues, housemate)
 }
 
+// rerouteDues makes everyone who owes the housemate owe the housemate's creditors instead,
+// so that removing a housemate with a zero balance keeps every other balance intact. The raw
+// dues are only a pairing of the posted entries, so they can be re-paired here without posting
+// anything: no account moves.
+func (g *GlobalMapStorage) rerouteDues(housemate string) {
+	var creditors, debtors []string
+	for _, name := range g.GetHousemateNames() {
+		if g.dues[name][housemate] > 0 {
+			creditors = append(creditors, name)
+		}
+		if g.dues[housemate][name] > 0 {
+			debtors = append(debtors, name)
+		}
+	}
+	sort.Strings(creditors)
+	sort.Strings(debtors)
+
+	i, j := 0, 0
+	for i < len(creditors) && j < len(debtors) {
+		creditor, debtor := creditors[i], debtors[j]
+		amount := g.dues[creditor][housemate]
+		if g.dues[housemate][debtor] < amount {
+			amount = g.dues[housemate][debtor]
+		}
+		g.dues[creditor][housemate] -= amount
+		g.dues[housemate][debtor] -= amount
+		if creditor != debtor {
+			g.offsetEdge(creditor, debtor, amount)
+		}
+		if g.dues[creditor][housemate] == model.ZERO_DUE {
+			i++
+		}
+		if g.dues[housemate][debtor] == model.ZERO_DUE {
+			j++
+		}
+	}
+}
+
 // cleanupDues removes all dues related to a housemate
 func (g *GlobalMapStorage) cleanupDues(housemate string) {
 	for name := range g.dues {
@@ -56,59 +127,84 @@ func (g *GlobalMapStorage) cleanupDues(housemate string) {
 	}
 }
 
-// AddOrUpdateDue adds or updates a due from one housemate to another
-func (g *GlobalMapStorage) AddOrUpdateDue(from, to string, amount int64) {
-	adjustDue(g.dues, from, to, amount)
-	if amount == model.ZERO_DUE {
-		adjustDue(g.simplifydues, from, to, amount)
+// offsetEdge records that to owes from an additional amount on the raw dues. Any due in
+// the opposite direction is reduced first, and a negative amount reduces what to owes
+// from, crossing over into a reverse due when it exceeds it.
+func (g *GlobalMapStorage) offsetEdge(from, to string, amount int64) {
+	if amount < 0 {
+		g.offsetEdge(to, from, -amount)
+		return
 	}
+	reverse := g.dues[to][from]
+	if reverse >= amount {
+		g.dues[to][from] = reverse - amount
+		return
+	}
+	g.dues[to][from] = model.ZERO_DUE
+	g.dues[from][to] += amount - reverse
 }
 
 // SimplifyDebt simplifies all dues by minimizing the transactions
 func (g *GlobalMapStorage) SimplifyDebt() {
-	inOuts := g.calculateNetBalances()
 	g.resetSimplifiedDues()
-	g.minimizeTransactions(inOuts)
+	for _, step := range minimizeTransactions(g.calculateNetBalances()) {
+		g.simplifydues[step.Debtor][step.Creditor] = step.Amount
+	}
+}
+
+// GetSimplificationSteps returns, in order, the settlements chosen when the dues were
+// simplified from the current account balances
+func (g *GlobalMapStorage) GetSimplificationSteps() []model.SimplificationStep {
+	return minimizeTransactions(g.calculateNetBalances())
 }
 
 // minimizeTransactions reduces the number of transactions required to settle debts
-func (g *GlobalMapStorage) minimizeTransactions(balances map[string]int64) {
+func minimizeTransactions(balances map[string]int64) []model.SimplificationStep {
 	nonZeroBalances := extractNonZeroBalances(balances)
 
 	if len(nonZeroBalances) == 0 {
-		return
+		return nil
 	}
 
 	minAmount, maxAmount := findMinMaxBalances(nonZeroBalances)
 	minHousemate := findHousemateByBalance(balances, minAmount)
 	maxHousemate := findHousemateByBalance(balances, maxAmount)
 
-	g.handleTransaction(balances, minHousemate, maxHousemate, minAmount, maxAmount)
+	step := handleTransaction(balances, minHousemate, maxHousemate, minAmount, maxAmount)
+	return append([]model.SimplificationStep{step}, minimizeTransactions(balances)...)
 }
 
-// handleTransaction processes a transaction between two housemates
-func (g *GlobalMapStorage) handleTransaction(balances map[string]int64, minHousemate, maxHousemate string, minAmount, maxAmount int64) {
+// handleTransaction settles the largest debtor against the largest creditor
+func handleTransaction(balances map[string]int64, minHousemate, maxHousemate string, minAmount, maxAmount int64) model.SimplificationStep {
+	step := model.SimplificationStep{
+		Debtor:          minHousemate,
+		Creditor:        maxHousemate,
+		DebtorBalance:   minAmount,
+		CreditorBalance: maxAmount,
+	}
 	leftAmount := maxAmount + minAmount
 	if leftAmount >= 0 {
-		g.processPositiveTransaction(balances, minHousemate, maxHousemate, minAmount, leftAmount)
+		step.Amount = processPositiveTransaction(balances, minHousemate, maxHousemate, minAmount, leftAmount)
 	} else {
-		g.processNegativeTransaction(balances, minHousemate, maxHousemate, maxAmount, leftAmount)
+		step.Amount = processNegativeTransaction(balances, minHousemate, maxHousemate, maxAmount, leftAmount)
 	}
-	g.minimizeTransactions(balances)
+	return step
 }
 
-// processPositiveTransaction processes a positive transaction between two housemates
-func (g *GlobalMapStorage) processPositiveTransaction(balances map[string]int64, minHousemate, maxHousemate string, minAmount, leftAmount int64) {
+// processPositiveTransaction clears the debtor, leaving the creditor with the rest, and
+// returns the amount settled
+func processPositiveTransaction(balances map[string]int64, minHousemate, maxHousemate string, minAmount, leftAmount int64) int64 {
 	balances[minHousemate] = 0
 	balances[maxHousemate] = leftAmount
-	g.simplifydues[minHousemate][maxHousemate] = int64(math.Abs(float64(minAmount)))
+	return int64(math.Abs(float64(minAmount)))
 }
 
-// processNegativeTransaction processes a negative transaction between two housemates
-func (g *GlobalMapStorage) processNegativeTransaction(balances map[string]int64, minHousemate, maxHousemate string, maxAmount, leftAmount int64) {
+// processNegativeTransaction clears the creditor, leaving the debtor with the rest, and
+// returns the amount settled
+func processNegativeTransaction(balances map[string]int64, minHousemate, maxHousemate string, maxAmount, leftAmount int64) int64 {
 	balances[minHousemate] = leftAmount
 	balances[maxHousemate] = 0
-	g.simplifydues[minHousemate][maxHousemate] = maxAmount
+	return maxAmount
 }
 
 // extractNonZeroBalances extracts non-zero balances from a map
@@ -139,22 +235,27 @@ func (g *GlobalMapStorage) resetSimplifiedDues() {
 	}
 }
 
-// findHousemateByBalance finds a housemate by their balance value
+// findHousemateByBalance finds the first housemate by name with the balance value, so that
+// ties are always broken the same way
 func findHousemateByBalance(balances map[string]int64, value int64) string {
-	for name, balance := range balances {
-		if balance == value {
+	names := make([]string, 0, len(balances))
+	for name := range balances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if balances[name] == value {
 			return name
 		}
 	}
 	return ""
 }
 
-// calculateNetBalances calculates the net balances for all housemates
+// calculateNetBalances returns the account balance of every housemate
 func (g *GlobalMapStorage) calculateNetBalances() map[string]int64 {
 	netBalances := make(map[string]int64)
 	for housemate := range g.housemates {
-		netBalances[housemate] = model.ZERO_DUE
-		netBalances[housemate] += g.GetInAmount(housemate) - g.GetOutAmount(housemate)
+		netBalances[housemate] = g.accounts[housemate]
 	}
 	return netBalances
 }
@@ -175,12 +276,6 @@ func (g *GlobalMapStorage) GetOutAmount(housemate string) int64 {
 	return amount
 }
 
-// ClearDues clears a due between two housemates
-func (g *GlobalMapStorage) ClearDues(from, to string, amount int64) {
-	adjustDue(g.dues, from, to, -amount)
-	adjustDue(g.simplifydues, from, to, -amount)
-}
-
 // GetNumberOfHousemates returns the number of housemates
 func (g *GlobalMapStorage) GetNumberOfHousemates() int {
 	return len(g.housemates)
@@ -195,11 +290,228 @@ func (g *GlobalMapStorage) GetAllDues(housemate string) map[string]int64 {
 	return copy
 }
 
+// RecordTransaction appends a transaction to the history and returns it with its assigned ID.
+// A transaction dated in a closed period is rejected and nothing changes.
+func (g *GlobalMapStorage) RecordTransaction(transaction model.Transaction) (model.Transaction, error) {
+	if err := g.CheckPeriodOpen(transaction.Date); err != nil {
+		return model.Transaction{}, err
+	}
+	transaction.ID = len(g.history) + 1
+	if transaction.Date.IsZero() {
+		transaction.Date = g.today
+	}
+	g.history = append(g.history, transaction)
+	return transaction, nil
+}
+
+// GetTransaction returns the recorded transaction with the given ID
+func (g *GlobalMapStorage) GetTransaction(id int) (model.Transaction, bool) {
+	if id < 1 || id > len(g.history) {
+		return model.Transaction{}, false
+	}
+	return g.history[id-1], true
+}
+
+// CategorizeLastTransaction sets the category of the most recently recorded transaction
+func (g *GlobalMapStorage) CategorizeLastTransaction(category string) {
+	if len(g.history) > 0 {
+		g.history[len(g.history)-1].Category = category
+	}
+}
+
+// GetHistory returns a copy of all recorded transactions in the order they happened
+func (g *GlobalMapStorage) GetHistory() []model.Transaction {
+	history := make([]model.Transaction, len(g.history))
+	copy(history, g.history)
+	return history
+}
+
+// SetSetting changes the value of a house setting
+func (g *GlobalMapStorage) SetSetting(setting model.Setting, value string) {
+	g.settings[setting] = value
+}
+
+// GetSetting returns the current value of a house setting
+func (g *GlobalMapStorage) GetSetting(setting model.Setting) string {
+	return g.settings[setting]
+}
+
+// SetDeposit records the security deposit a housemate paid to the holder
+func (g *GlobalMapStorage) SetDeposit(housemate string, deposit model.Deposit) {
+	g.deposits[housemate] = deposit
+}
+
+// GetDeposit returns the security deposit paid by a housemate, if any
+func (g *GlobalMapStorage) GetDeposit(housemate string) (model.Deposit, bool) {
+	deposit, ok := g.deposits[housemate]
+	return deposit, ok
+}
+
+// RemoveDeposit forgets the security deposit paid by a housemate
+func (g *GlobalMapStorage) RemoveDeposit(housemate string) {
+	delete(g.deposits, housemate)
+}
+
+// GetDeposits returns a copy of all security deposits keyed by the housemate who paid them
+func (g *GlobalMapStorage) GetDeposits() map[string]model.Deposit {
+	deposits := make(map[string]model.Deposit, len(g.deposits))
+	for housemate, deposit := range g.deposits {
+		deposits[housemate] = deposit
+	}
+	return deposits
+}
+
+// AdjustKittyShare changes a housemate's share of the house kitty; top-ups raise it and
+// kitty spending on the housemate lowers it, possibly below zero
+func (g *GlobalMapStorage) AdjustKittyShare(housemate string, amount int64) {
+	g.kitty[housemate] += amount
+	if g.kitty[housemate] == model.ZERO_DUE {
+		delete(g.kitty, housemate)
+	}
+}
+
+// GetKittyShares returns a copy of every non-zero share of the house kitty
+func (g *GlobalMapStorage) GetKittyShares() map[string]int64 {
+	shares := make(map[string]int64, len(g.kitty))
+	for housemate, share := range g.kitty {
+		shares[housemate] = share
+	}
+	return shares
+}
+
+// GetKittyBalance returns the money currently held in the house kitty
+func (g *GlobalMapStorage) GetKittyBalance() int64 {
+	var balance int64
+	for _, share := range g.kitty {
+		balance += share
+	}
+	return balance
+}
+
+// SetToday sets the house date used to stamp moves and transactions
+func (g *GlobalMapStorage) SetToday(date time.Time) {
+	g.today = date
+}
+
+// GetToday returns the current house date, or zero if none was set
+func (g *GlobalMapStorage) GetToday() time.Time {
+	return g.today
+}
+
+// GetStays returns the intervals a housemate lived in the house; the last one is open while they live there
+func (g *GlobalMapStorage) GetStays(housemate string) []model.Interval {
+	return append([]model.Interval(nil), g.stays[housemate]...)
+}
+
+// AddAway records an interval during which a housemate was away
+func (g *GlobalMapStorage) AddAway(housemate string, interval model.Interval) {
+	g.aways[housemate] = append(g.aways[housemate], interval)
+}
+
+// GetAways returns the intervals a housemate declared they were away
+func (g *GlobalMapStorage) GetAways(housemate string) []model.Interval {
+	return append([]model.Interval(nil), g.aways[housemate]...)
+}
+
+// SetSplit stores a named profile of split weights per housemate
+func (g *GlobalMapStorage) SetSplit(name string, weights map[string]int64) {
+	g.splits[name] = weights
+}
+
+// GetSplit returns a copy of the weights of a named split profile
+func (g *GlobalMapStorage) GetSplit(name string) (map[string]int64, bool) {
+	weights, ok := g.splits[name]
+	if !ok {
+		return nil, false
+	}
+	copy := make(map[string]int64, len(weights))
+	for housemate, weight := range weights {
+		copy[housemate] = weight
+	}
+	return copy, true
+}
+
+// RecordImport remembers the fingerprint of an imported statement row
+func (g *GlobalMapStorage) RecordImport(fingerprint string) {
+	g.imports[fingerprint] = true
+}
+
+// IsImported reports whether a statement row with the fingerprint was already imported
+func (g *GlobalMapStorage) IsImported(fingerprint string) bool {
+	return g.imports[fingerprint]
+}
+
+// GetNetBalances returns the net balance of every housemate, positive for creditors
+func (g *GlobalMapStorage) GetNetBalances() map[string]int64 {
+	return g.calculateNetBalances()
+}
+
 // GetTransactions returns all simplified dues
 func (g *GlobalMapStorage) GetTransactions() map[string]map[string]int64 {
 	return g.simplifydues
 }
 
+// ClosePeriod locks the month of the period and keeps its closing position
+func (g *GlobalMapStorage) ClosePeriod(period model.ClosedPeriod) {
+	g.closed[period.Month] = period
+}
+
+// ReopenPeriod unlocks a closed month
+func (g *GlobalMapStorage) ReopenPeriod(month string) {
+	delete(g.closed, month)
+}
+
+// GetClosedPeriod returns the closed period of a month, if it is closed
+func (g *GlobalMapStorage) GetClosedPeriod(month string) (model.ClosedPeriod, bool) {
+	period, ok := g.closed[month]
+	return period, ok
+}
+
+// CheckPeriodOpen returns PERIOD_CLOSED when a transaction dated on date, or on the house
+// date when it is zero, would change a closing position: when it falls on or before the
+// latest closed month, or is undated while any month is closed
+func (g *GlobalMapStorage) CheckPeriodOpen(date time.Time) error {
+	if date.IsZero() {
+		date = g.today
+	}
+	latest := g.GetLatestClosedMonth()
+	if latest == "" {
+		return nil
+	}
+	if date.IsZero() || date.Format(model.MonthLayout) <= latest {
+		return errors.New(string(model.PERIOD_CLOSED))
+	}
+	return nil
+}
+
+// GetLatestClosedMonth returns the latest closed month, or "" when no month is closed
+func (g *GlobalMapStorage) GetLatestClosedMonth() string {
+	latest := ""
+	for month := range g.closed {
+		if month > latest {
+			latest = month
+		}
+	}
+	return latest
+}
+
+// RecordAudit appends a record to the audit log
+func (g *GlobalMapStorage) RecordAudit(record model.AuditRecord) {
+	g.audit = append(g.audit, record)
+}
+
+// GetAudit returns a copy of the audit log, in order
+func (g *GlobalMapStorage) GetAudit() []model.AuditRecord {
+	audit := make([]model.AuditRecord, len(g.audit))
+	copy(audit, g.audit)
+	return audit
+}
+
+// GetRawDues returns all dues before simplification, keyed creditor -> debtor
+func (g *GlobalMapStorage) GetRawDues() map[string]map[string]int64 {
+	return g.dues
+}
+
 // GetDue returns the due between two housemates
 func (g *GlobalMapStorage) GetDue(from, to string) int64 {
 	return g.simplifydues[from][to]
@@ -224,18 +536,33 @@ func (g *GlobalMapStorage) GetHousemateNames() []string {
 	return housemates
 }
 
-// adjustDue is a helper function to adjust dues between housemates
-func adjustDue(mapData map[string]map[string]int64, from, to string, amount int64) {
-	newAmount := mapData[from][to] + amount
-	if newAmount < 0 {
-		return
+// GetFormerHousemateNames returns the sorted names of everyone who has moved out
+func (g *GlobalMapStorage) GetFormerHousemateNames() []string {
+	var former []string
+	for housemate := range g.former {
+		former = append(former, housemate)
 	}
-	mapData[from][to] = newAmount
+	sort.Strings(former)
+	return former
 }
 
 // Reset resets the storage to its initial state
 func (g *GlobalMapStorage) Reset() {
 	g.housemates = make(map[string]bool)
+	g.former = make(map[string]bool)
 	g.dues = make(map[string]map[string]int64)
 	g.simplifydues = make(map[string]map[string]int64)
+	g.history = nil
+	g.settings = model.DefaultSettings()
+	g.deposits = make(map[string]model.Deposit)
+	g.kitty = make(map[string]int64)
+	g.today = time.Time{}
+	g.stays = make(map[string][]model.Interval)
+	g.aways = make(map[string][]model.Interval)
+	g.splits = make(map[string]map[string]int64)
+	g.imports = make(map[string]bool)
+	g.accounts = make(map[string]int64)
+	g.entries = nil
+	g.closed = make(map[string]model.ClosedPeriod)
+	g.audit = nil
 }
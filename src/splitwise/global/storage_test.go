@@ -1,8 +1,12 @@
 package global
 
-import "testing"
+import (
+	"splitwise/model"
+	"testing"
+	"time"
+)
 
-func TestAddOrUpdateDue(t *testing.T) {
+func TestProjectDues(t *testing.T) {
 	globalStorage := NewGlobalMapStorage()
 
 	// Add housemates
@@ -19,29 +23,36 @@ func TestAddOrUpdateDue(t *testing.T) {
 	}
 
 	// TEST CASE 2: Add dues for the first time
-	globalStorage.AddOrUpdateDue("Andy", "Woody", 1000)
-	globalStorage.AddOrUpdateDue("Woody", "Andy", 500)
+	lend(globalStorage, "Andy", "Woody", 1000)
 
 	// Check dues
 	if globalStorage.dues["Andy"]["Woody"] != 1000 {
 		t.Errorf("Expected 1000, got %d", globalStorage.dues["Andy"]["Woody"])
 	}
 
-	if globalStorage.dues["Woody"]["Andy"] != 500 {
-		t.Errorf("Expected 500, got %d", globalStorage.dues["Woody"]["Andy"])
+	// TEST CASE 3: A due in the opposite direction is offset
+	lend(globalStorage, "Woody", "Andy", 500)
+
+	// Check dues
+	if globalStorage.dues["Andy"]["Woody"] != 500 {
+		t.Errorf("Expected 500, got %d", globalStorage.dues["Andy"]["Woody"])
+	}
+
+	if globalStorage.dues["Woody"]["Andy"] != 0 {
+		t.Errorf("Expected 0, got %d", globalStorage.dues["Woody"]["Andy"])
 	}
 
-	// TEST CASE 3: Update dues for existing housemates
-	globalStorage.AddOrUpdateDue("Andy", "Woody", 2000)
-	globalStorage.AddOrUpdateDue("Woody", "Andy", 1000)
+	// TEST CASE 4: Update dues for existing housemates
+	lend(globalStorage, "Andy", "Woody", 2000)
+	lend(globalStorage, "Woody", "Andy", 1000)
 
 	// Check dues
-	if globalStorage.dues["Andy"]["Woody"] != 3000 {
-		t.Errorf("Expected 3000, got %d", globalStorage.dues["Andy"]["Woody"])
+	if globalStorage.dues["Andy"]["Woody"] != 1500 {
+		t.Errorf("Expected 1500, got %d", globalStorage.dues["Andy"]["Woody"])
 	}
 
-	if globalStorage.dues["Woody"]["Andy"] != 1500 {
-		t.Errorf("Expected 1500, got %d", globalStorage.dues["Woody"]["Andy"])
+	if globalStorage.dues["Woody"]["Andy"] != 0 {
+		t.Errorf("Expected 0, got %d", globalStorage.dues["Woody"]["Andy"])
 	}
 }
 
@@ -90,14 +101,10 @@ func TestClearDues(t *testing.T) {
 	globalStorage.AddHousemate("Woody")
 
 	// Add dues for housemates
-	globalStorage.AddOrUpdateDue("Andy", "Woody", 1000)
-	globalStorage.AddOrUpdateDue("Woody", "Andy", 500)
-
-	// Simplify debt before clearing dues
-	globalStorage.SimplifyDebt()
+	lend(globalStorage, "Andy", "Woody", 1000)
 
 	// TEST CASE 1: Clear dues between housemates
-	globalStorage.ClearDues("Andy", "Woody", 500)
+	pay(globalStorage, "Woody", "Andy", 500)
 
 	// Check if dues are cleared for Andy (Woody -> Andy = 500)
 	if data := globalStorage.GetNonShuffledDue("Andy", "Woody"); data != 500 {
@@ -110,16 +117,16 @@ func TestClearDues(t *testing.T) {
 	}
 
 	// TEST CASE 2: Clear dues between housemates
-	globalStorage.ClearDues("Woody", "Andy", 500)
+	pay(globalStorage, "Woody", "Andy", 500)
 
-	// Check if dues are cleared for Woody (Woody -> Andy = 0)
-	if data := globalStorage.GetNonShuffledDue("Woody", "Andy"); data != 0 {
-		t.Errorf("Expected dues between Woody and Andy to be 0, got %d", data)
+	// Check if dues are cleared for Andy (Woody -> Andy = 0)
+	if data := globalStorage.GetNonShuffledDue("Andy", "Woody"); data != 0 {
+		t.Errorf("Expected dues between Andy and Woody to be 0, got %d", data)
 	}
 
 	// Check if dues are cleared for Woody (Woody -> Andy = 0)
-	if data := globalStorage.GetNonShuffledDue("Woody", "Andy"); data != 0 {
-		t.Errorf("Expected dues between Woody and Andy to be 0, got %d", data)
+	if globalStorage.simplifydues["Woody"]["Andy"] != 0 {
+		t.Errorf("Expected dues between Woody and Andy to be 0, got %d", globalStorage.simplifydues["Woody"]["Andy"])
 	}
 }
 
@@ -132,10 +139,10 @@ func TestSimplifyDebt(t *testing.T) {
 	globalStorage.AddHousemate("Buzz")
 
 	// Add dues
-	globalStorage.AddOrUpdateDue("Andy", "Woody", 1000)
-	globalStorage.AddOrUpdateDue("Woody", "Andy", 500)
-	globalStorage.AddOrUpdateDue("Buzz", "Andy", 2000)
-	globalStorage.AddOrUpdateDue("Buzz", "Woody", 1500)
+	lend(globalStorage, "Andy", "Woody", 1000)
+	lend(globalStorage, "Woody", "Andy", 500)
+	lend(globalStorage, "Buzz", "Andy", 2000)
+	lend(globalStorage, "Buzz", "Woody", 1500)
 
 	// Simplify debt
 	globalStorage.SimplifyDebt()
@@ -162,7 +169,7 @@ func TestSimplifyDebt(t *testing.T) {
 	}
 
 	// Add more dues
-	globalStorage.AddOrUpdateDue("Andy", "Buzz", 1500)
+	lend(globalStorage, "Andy", "Buzz", 1500)
 
 	// Simplify debt
 	globalStorage.SimplifyDebt()
@@ -189,3 +196,292 @@ func TestSimplifyDebt(t *testing.T) {
 	}
 
 }
+
+func TestRecordTransaction(t *testing.T) {
+	globalStorage := NewGlobalMapStorage()
+
+	// TEST CASE 1: IDs are assigned in order
+	first, _ := globalStorage.RecordTransaction(model.Transaction{Type: model.LOAN_TRANSACTION, Amount: 500})
+	second, _ := globalStorage.RecordTransaction(model.Transaction{Type: model.PAID_FOR_TRANSACTION, Amount: 300})
+	if first.ID != 1 || second.ID != 2 {
+		t.Errorf("Expected IDs 1 and 2, got %d and %d", first.ID, second.ID)
+	}
+
+	// TEST CASE 2: History keeps the order and the transaction types
+	history := globalStorage.GetHistory()
+	if len(history) != 2 || history[0].Type != model.LOAN_TRANSACTION || history[1].Type != model.PAID_FOR_TRANSACTION {
+		t.Errorf("Expected LEND then PAY_FOR in history, got %v", history)
+	}
+
+	// TEST CASE 3: Reset clears the history
+	globalStorage.Reset()
+	if len(globalStorage.GetHistory()) != 0 {
+		t.Errorf("Expected empty history after reset")
+	}
+}
+
+func TestOffsetEdge(t *testing.T) {
+	globalStorage := NewGlobalMapStorage()
+
+	// Add housemates
+	globalStorage.AddHousemate("Andy")
+	globalStorage.AddHousemate("Woody")
+
+	// TEST CASE 1: A positive offset adds to the due
+	globalStorage.offsetEdge("Andy", "Woody", 1000)
+	if data := globalStorage.GetNonShuffledDue("Andy", "Woody"); data != 1000 {
+		t.Errorf("Expected 1000, got %d", data)
+	}
+
+	// TEST CASE 2: A negative offset reduces the due
+	globalStorage.offsetEdge("Andy", "Woody", -400)
+	if data := globalStorage.GetNonShuffledDue("Andy", "Woody"); data != 600 {
+		t.Errorf("Expected 600, got %d", data)
+	}
+
+	// TEST CASE 3: Offsetting past zero reverses the due
+	globalStorage.offsetEdge("Woody", "Andy", 1000)
+	if data := globalStorage.GetNonShuffledDue("Andy", "Woody"); data != 0 {
+		t.Errorf("Expected 0, got %d", data)
+	}
+	if data := globalStorage.GetNonShuffledDue("Woody", "Andy"); data != 400 {
+		t.Errorf("Expected 400, got %d", data)
+	}
+}
+
+func TestRemoveHousemateReroutesDues(t *testing.T) {
+	globalStorage := NewGlobalMapStorage()
+
+	// Add housemates
+	globalStorage.AddHousemate("Andy")
+	globalStorage.AddHousemate("Woody")
+	globalStorage.AddHousemate("Buzz")
+
+	// Buzz owes Andy and Woody owes Buzz the same amount, so Buzz has a zero balance
+	lend(globalStorage, "Andy", "Buzz", 700)
+	lend(globalStorage, "Buzz", "Woody", 700)
+
+	globalStorage.RemoveHousemate("Buzz")
+
+	// TEST CASE 1: Woody now owes Andy directly
+	if data := globalStorage.GetNonShuffledDue("Andy", "Woody"); data != 700 {
+		t.Errorf("Expected Woody to owe Andy 700, got %d", data)
+	}
+
+	// TEST CASE 2: Balances of the remaining housemates are unchanged
+	balances := globalStorage.calculateNetBalances()
+	if balances["Andy"] != 700 || balances["Woody"] != -700 {
+		t.Errorf("Expected balances 700 and -700, got %d and %d", balances["Andy"], balances["Woody"])
+	}
+}
+
+func TestFormerHousemates(t *testing.T) {
+	globalStorage := NewGlobalMapStorage()
+
+	// Add housemates
+	globalStorage.AddHousemate("Andy")
+	globalStorage.AddHousemate("Woody")
+
+	// TEST CASE 1: A removed housemate is archived and no longer counted
+	globalStorage.RemoveHousemate("Andy")
+	if former := globalStorage.GetFormerHousemateNames(); len(former) != 1 || former[0] != "Andy" {
+		t.Errorf("Expected Andy to be a former housemate, got %v", former)
+	}
+	if globalStorage.GetNumberOfHousemates() != 1 {
+		t.Errorf("Expected 1 housemate, got %d", globalStorage.GetNumberOfHousemates())
+	}
+
+	// TEST CASE 2: Moving back in removes the housemate from the archive
+	globalStorage.AddHousemate("Andy")
+	if former := globalStorage.GetFormerHousemateNames(); len(former) != 0 {
+		t.Errorf("Expected no former housemates, got %v", former)
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	globalStorage := NewGlobalMapStorage()
+
+	// Add housemates and dues
+	globalStorage.AddHousemate("Andy")
+	globalStorage.AddHousemate("Woody")
+	lend(globalStorage, "Andy", "Woody", 700)
+	globalStorage.SetSetting(model.OVERPAYMENT, string(model.OVERPAYMENT_CREDIT))
+	globalStorage.ClosePeriod(model.ClosedPeriod{Month: "2026-01", Balances: map[string]int64{"Andy": 700, "Woody": -700}})
+	globalStorage.RecordAudit(model.AuditRecord{Action: model.CLOSE_PERIOD, Month: "2026-01"})
+
+	// TEST CASE 1: A restored storage matches the original
+	restored := NewGlobalMapStorage()
+	restored.Restore(globalStorage.Snapshot())
+	if restored.GetNumberOfHousemates() != 2 {
+		t.Errorf("Expected 2 housemates, got %d", restored.GetNumberOfHousemates())
+	}
+	if restored.GetDue("Woody", "Andy") != globalStorage.GetDue("Woody", "Andy") {
+		t.Errorf("Expected due %d, got %d", globalStorage.GetDue("Woody", "Andy"), restored.GetDue("Woody", "Andy"))
+	}
+	if len(restored.GetHistory()) != 1 {
+		t.Errorf("Expected 1 transaction, got %d", len(restored.GetHistory()))
+	}
+	if restored.GetSetting(model.OVERPAYMENT) != string(model.OVERPAYMENT_CREDIT) {
+		t.Errorf("Expected setting CREDIT, got %s", restored.GetSetting(model.OVERPAYMENT))
+	}
+	if period, ok := restored.GetClosedPeriod("2026-01"); !ok || period.Balances["Woody"] != -700 || len(restored.GetAudit()) != 1 {
+		t.Errorf("Expected the closed period and its audit record, got %+v and %+v", period, restored.GetAudit())
+	}
+
+	// TEST CASE 2: The restored storage does not share state with the original
+	restored.dues["Andy"]["Woody"] += 100
+	if globalStorage.dues["Andy"]["Woody"] != 700 {
+		t.Errorf("Expected 700, got %d", globalStorage.dues["Andy"]["Woody"])
+	}
+}
+
+func TestPostTransaction(t *testing.T) {
+	globalStorage := NewGlobalMapStorage()
+
+	// Add housemates
+	globalStorage.AddHousemate("Andy")
+	globalStorage.AddHousemate("Woody")
+	globalStorage.AddHousemate("Buzz")
+
+	// TEST CASE 1: A balanced transaction posts one entry and projects the dues
+	recorded, err := globalStorage.PostTransaction(model.Transaction{
+		Type:   model.EXPENSE_TRANSACTION,
+		Amount: 900,
+		Payers: map[string]int64{"Andy": 900},
+		Shares: map[string]int64{"Andy": 300, "Woody": 300, "Buzz": 300},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	entries := globalStorage.GetEntries()
+	if len(entries) != 1 || entries[0].TransactionID != recorded.ID || len(entries[0].Postings) != 3 {
+		t.Errorf("Expected one entry with 3 postings for transaction %d, got %+v", recorded.ID, entries)
+	}
+	accounts := globalStorage.GetAccounts()
+	if accounts["Andy"] != 600 || accounts["Woody"] != -300 || accounts["Buzz"] != -300 {
+		t.Errorf("Expected accounts 600, -300 and -300, got %v", accounts)
+	}
+	for name, balance := range accounts {
+		if in := globalStorage.GetInAmount(name) - globalStorage.GetOutAmount(name); in != balance {
+			t.Errorf("Expected the dues of %s to net to %d, got %d", name, balance, in)
+		}
+	}
+	if globalStorage.GetDue("Woody", "Andy") != 300 || globalStorage.GetDue("Buzz", "Andy") != 300 {
+		t.Errorf("Expected Woody and Buzz to owe Andy 300")
+	}
+
+	// TEST CASE 2: An unbalanced transaction is rejected and changes nothing
+	_, err = globalStorage.PostTransaction(model.Transaction{
+		Type:   model.EXPENSE_TRANSACTION,
+		Amount: 100,
+		Payers: map[string]int64{"Woody": 100},
+		Shares: map[string]int64{"Andy": 90},
+	})
+	if err == nil || err.Error() != string(model.UNBALANCED_POSTING) {
+		t.Errorf("Expected %s, got %v", model.UNBALANCED_POSTING, err)
+	}
+	if len(globalStorage.GetEntries()) != 1 || len(globalStorage.GetHistory()) != 1 {
+		t.Errorf("Expected the rejected transaction to leave no trace")
+	}
+	if globalStorage.GetAccounts()["Woody"] != -300 {
+		t.Errorf("Expected Woody's account to stay at -300, got %d", globalStorage.GetAccounts()["Woody"])
+	}
+}
+
+func TestCheckInvariants(t *testing.T) {
+	globalStorage := NewGlobalMapStorage()
+
+	// Add housemates
+	globalStorage.AddHousemate("Andy")
+	globalStorage.AddHousemate("Woody")
+	globalStorage.AddHousemate("Buzz")
+
+	// TEST CASE 1: Books kept through the storage are consistent
+	lend(globalStorage, "Andy", "Woody", 300)
+	lend(globalStorage, "Woody", "Buzz", 200)
+	if violations := globalStorage.CheckInvariants(); len(violations) != 0 {
+		t.Errorf("Expected no violations, got %v", violations)
+	}
+
+	// TEST CASE 2: Dues edited behind the accounts are reported precisely
+	globalStorage.dues["Andy"]["Andy"] = 50
+	globalStorage.simplifydues["Buzz"]["Andy"] = -100
+	expected := []string{
+		"NEGATIVE_EDGE simplified_dues Buzz Andy -100",
+		"NET_MISMATCH Andy ACCOUNT 300 DUES 300 SIMPLIFIED 0",
+		"NET_MISMATCH Buzz ACCOUNT -200 DUES -200 SIMPLIFIED 100",
+		"SELF_EDGE dues Andy 50",
+	}
+	violations := globalStorage.CheckInvariants()
+	if len(violations) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, violations)
+	}
+	for i := range expected {
+		if violations[i] != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], violations[i])
+		}
+	}
+
+	// TEST CASE 3: Accounts that do not sum to zero are reported
+	globalStorage.Reset()
+	globalStorage.AddHousemate("Andy")
+	globalStorage.accounts["Andy"] = 10
+	violations = globalStorage.CheckInvariants()
+	if len(violations) != 2 || violations[1] != "NONZERO_SUM 10" {
+		t.Errorf("Expected a NET_MISMATCH and NONZERO_SUM 10, got %v", violations)
+	}
+}
+
+func TestCheckPeriodOpen(t *testing.T) {
+	globalStorage := NewGlobalMapStorage()
+	january := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// TEST CASE 1: Every date is open while no month is closed
+	if err := globalStorage.CheckPeriodOpen(time.Time{}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	// TEST CASE 2: Closing a month locks it, the months before it and undated transactions
+	globalStorage.ClosePeriod(model.ClosedPeriod{Month: "2026-02"})
+	for _, date := range []time.Time{january, {}} {
+		if err := globalStorage.CheckPeriodOpen(date); err == nil || err.Error() != string(model.PERIOD_CLOSED) {
+			t.Errorf("Expected %s for %v, got %v", model.PERIOD_CLOSED, date, err)
+		}
+	}
+	if err := globalStorage.CheckPeriodOpen(march); err != nil {
+		t.Errorf("Expected March to be open, got %v", err)
+	}
+
+	// TEST CASE 3: The house date stands in for an undated transaction
+	globalStorage.SetToday(march)
+	if _, err := globalStorage.RecordTransaction(model.Transaction{Type: model.KITTY_TOPUP_TRANSACTION}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if _, err := globalStorage.RecordTransaction(model.Transaction{Type: model.KITTY_TOPUP_TRANSACTION, Date: january}); err == nil {
+		t.Errorf("Expected a back-dated transaction to be rejected")
+	}
+	if len(globalStorage.GetHistory()) != 1 {
+		t.Errorf("Expected only the open transaction in the history, got %v", globalStorage.GetHistory())
+	}
+}
+
+// lend posts a loan from the lender to the borrower
+func lend(g *GlobalMapStorage, lender, borrower string, amount int64) {
+	g.PostTransaction(model.Transaction{
+		Type:   model.LOAN_TRANSACTION,
+		Amount: amount,
+		Payers: map[string]int64{lender: amount},
+		Shares: map[string]int64{borrower: amount},
+	})
+}
+
+// pay posts a payment of a due from the debtor to the creditor
+func pay(g *GlobalMapStorage, debtor, creditor string, amount int64) {
+	g.PostTransaction(model.Transaction{
+		Type:   model.PAYMENT_TRANSACTION,
+		Amount: amount,
+		Payers: map[string]int64{debtor: amount},
+		Shares: map[string]int64{creditor: amount},
+	})
+}
@@ -0,0 +1,80 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Mapping describes how the rows of a bank or card statement become expenses. It is read
+// from a JSON file such as:
+//
+//	{
+//	  "date": "Posted", "amount": "Amount", "description": "Details",
+//	  "date_format": "01/02/2006", "negate": true,
+//	  "payer": "ANDY", "beneficiaries": ["ANDY", "WOODY"],
+//	  "rules": [{"match": "netflix", "category": "streaming", "split": "ROOMS"}]
+//	}
+type Mapping struct {
+	// Date, Amount and Description name the statement columns holding each value.
+	Date        string `json:"date"`
+	Amount      string `json:"amount"`
+	Description string `json:"description"`
+	// DateFormat is the Go layout of the dates, yyyy-mm-dd by default.
+	DateFormat string `json:"date_format,omitempty"`
+	// Negate flips the sign of the amounts, for statements that list charges as negative.
+	Negate bool `json:"negate,omitempty"`
+	// Payer and Beneficiaries are used for rows that no rule changes.
+	Payer         string   `json:"payer"`
+	Beneficiaries []string `json:"beneficiaries"`
+	// Rules are tried in order and the first one matching a row's description applies.
+	Rules []Rule `json:"rules,omitempty"`
+}
+
+// Rule changes how the rows whose description contains Match, ignoring case, are imported.
+type Rule struct {
+	Match         string   `json:"match"`
+	Category      string   `json:"category,omitempty"`
+	Payer         string   `json:"payer,omitempty"`
+	Beneficiaries []string `json:"beneficiaries,omitempty"`
+	// Split names a split profile that shares the expense instead of the beneficiaries.
+	Split string `json:"split,omitempty"`
+	// Skip leaves the matching rows out, such as card payments or personal purchases.
+	Skip bool `json:"skip,omitempty"`
+}
+
+// LoadMapping reads and checks the mapping file at path.
+func LoadMapping(path string) (Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Mapping{}, fmt.Errorf("error reading the mapping: %w", err)
+	}
+	var mapping Mapping
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return Mapping{}, fmt.Errorf("error decoding the mapping: %w", err)
+	}
+	if mapping.Date == "" || mapping.Amount == "" || mapping.Description == "" {
+		return Mapping{}, fmt.Errorf("the mapping must name the date, amount and description columns")
+	}
+	if mapping.Payer == "" {
+		return Mapping{}, fmt.Errorf("the mapping must name a default payer")
+	}
+	for _, rule := range mapping.Rules {
+		if rule.Match == "" {
+			return Mapping{}, fmt.Errorf("every mapping rule must have a match")
+		}
+	}
+	return mapping, nil
+}
+
+// rule returns the first rule matching the description, or the zero rule if none does.
+func (m Mapping) rule(description string) Rule {
+	description = strings.ToLower(description)
+	for _, rule := range m.Rules {
+		if strings.Contains(description, strings.ToLower(rule.Match)) {
+			return rule
+		}
+	}
+	return Rule{}
+}
@@ -0,0 +1,254 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"splitwise/model"
+)
+
+// Columns of a Splitwise group export before the member columns.
+const (
+	splitwiseDate = iota
+	splitwiseDescription
+	splitwiseCategory
+	splitwiseCost
+	splitwiseCurrency
+	splitwiseMembers
+)
+
+const (
+	// splitwiseTotal is the description of the last row, holding the final balances.
+	splitwiseTotal = "Total balance"
+	// splitwisePayment is the category of rows recording a payment between members.
+	splitwisePayment = "Payment"
+	// centsPerUnit converts the cents of the export to the whole units of the house.
+	centsPerUnit = 100
+)
+
+// SplitwiseRow is one expense or payment of a Splitwise group export.
+type SplitwiseRow struct {
+	// Row is the line of the row in the export, the header being line 1.
+	Row         int
+	Date        time.Time
+	Description string
+	Category    string
+	Cost        int64
+	// Effects holds what each member paid minus their share, in whole units.
+	Effects map[string]int64
+}
+
+// SplitwiseExport is a Splitwise group export converted to whole units. Rows are rounded
+// so that every row still sums to zero and the rows of each member add up to their total.
+type SplitwiseExport struct {
+	// Members are the member names of the export as house names: upper case, with
+	// underscores for spaces.
+	Members []string
+	Rows    []SplitwiseRow
+	// Totals holds the final balance of each member given by the "Total balance" row.
+	Totals map[string]int64
+}
+
+// ReadSplitwise reads a Splitwise group export: date, description, category, cost and
+// currency columns followed by one column per member, ending with a "Total balance" row.
+func ReadSplitwise(r io.Reader) (SplitwiseExport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return SplitwiseExport{}, fmt.Errorf("error reading the export header: %w", err)
+	}
+	if len(header) <= splitwiseMembers {
+		return SplitwiseExport{}, fmt.Errorf("the export has no member columns")
+	}
+	export := SplitwiseExport{Totals: make(map[string]int64)}
+	for _, column := range header[splitwiseMembers:] {
+		name := memberName(column)
+		if name == "" || indexOfName(export.Members, name) >= 0 {
+			return SplitwiseExport{}, fmt.Errorf("invalid member column %q", column)
+		}
+		export.Members = append(export.Members, name)
+	}
+
+	var currency string
+	running := make(map[string]int64)
+	rounded := make(map[string]int64)
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return SplitwiseExport{}, fmt.Errorf("the export has no %q row", splitwiseTotal)
+		}
+		if err != nil {
+			return SplitwiseExport{}, fmt.Errorf("error reading the export: %w", err)
+		}
+		if len(record) != len(header) {
+			return SplitwiseExport{}, fmt.Errorf("row %d has %d columns instead of %d", row, len(record), len(header))
+		}
+		if currency == "" {
+			currency = record[splitwiseCurrency]
+		} else if record[splitwiseCurrency] != currency {
+			return SplitwiseExport{}, fmt.Errorf("row %d is in %s while the export is in %s", row, record[splitwiseCurrency], currency)
+		}
+
+		cents := make(map[string]int64, len(export.Members))
+		var sum int64
+		for i, member := range export.Members {
+			value, err := parseCents(record[splitwiseMembers+i])
+			if err != nil {
+				return SplitwiseExport{}, fmt.Errorf("row %d has an invalid amount for %s: %w", row, member, err)
+			}
+			cents[member] = value
+			sum += value
+		}
+		if sum != 0 {
+			return SplitwiseExport{}, fmt.Errorf("row %d does not balance", row)
+		}
+
+		if strings.EqualFold(strings.TrimSpace(record[splitwiseDescription]), splitwiseTotal) {
+			for _, member := range export.Members {
+				if cents[member] != running[member] {
+					return SplitwiseExport{}, fmt.Errorf("the rows do not add up to the total balance of %s", member)
+				}
+			}
+			export.Totals = rounded
+			return export, nil
+		}
+
+		date, err := time.Parse(model.DateLayout, strings.TrimSpace(record[splitwiseDate]))
+		if err != nil {
+			return SplitwiseExport{}, fmt.Errorf("row %d has an invalid date: %w", row, err)
+		}
+		cost, err := parseCents(record[splitwiseCost])
+		if err != nil {
+			return SplitwiseExport{}, fmt.Errorf("row %d has an invalid cost: %w", row, err)
+		}
+
+		// Round the running balances rather than each row, so rounding never accumulates
+		for member, value := range cents {
+			running[member] += value
+		}
+		next := roundCents(running)
+		effects := make(map[string]int64)
+		for _, member := range export.Members {
+			if effect := next[member] - rounded[member]; effect != 0 {
+				effects[member] = effect
+			}
+		}
+		rounded = next
+
+		export.Rows = append(export.Rows, SplitwiseRow{
+			Row:         row,
+			Date:        date,
+			Description: strings.TrimSpace(record[splitwiseDescription]),
+			Category:    strings.TrimSpace(record[splitwiseCategory]),
+			Cost:        int64(math.Round(float64(cost) / centsPerUnit)),
+			Effects:     effects,
+		})
+	}
+}
+
+// Transaction returns the row as a transaction of the house. Payments become CLEAR_DUE
+// and everything else SPEND in the row's category. A row with a single payer records the whole cost as paid by
+// them; otherwise the payers and shares are the positive and negative effects.
+func (r SplitwiseRow) Transaction() model.Transaction {
+	transaction := model.Transaction{
+		Date:   r.Date,
+		Type:   model.EXPENSE_TRANSACTION,
+		Payers: make(map[string]int64),
+		Shares: make(map[string]int64),
+	}
+	if r.Category == splitwisePayment {
+		transaction.Type = model.PAYMENT_TRANSACTION
+	} else {
+		transaction.Category = r.Category
+	}
+	for member, effect := range r.Effects {
+		if effect > 0 {
+			transaction.Payers[member] = effect
+			transaction.Amount += effect
+		} else {
+			transaction.Shares[member] = -effect
+		}
+	}
+	if len(transaction.Payers) == 1 && transaction.Type == model.EXPENSE_TRANSACTION {
+		for payer, effect := range transaction.Payers {
+			if r.Cost > effect {
+				transaction.Payers[payer] = r.Cost
+				transaction.Shares[payer] = r.Cost - effect
+				transaction.Amount = r.Cost
+			}
+		}
+	}
+	return transaction
+}
+
+// roundCents converts amounts in cents that sum to zero into whole units that still sum to
+// zero. Units lost to rounding down go to the largest remainders, ties broken by name.
+func roundCents(cents map[string]int64) map[string]int64 {
+	names := make([]string, 0, len(cents))
+	for name := range cents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	units := make(map[string]int64, len(names))
+	remainders := make(map[string]int64, len(names))
+	var leftover int64
+	for _, name := range names {
+		units[name] = floorDiv(cents[name], centsPerUnit)
+		remainders[name] = cents[name] - units[name]*centsPerUnit
+		leftover -= units[name]
+	}
+
+	sort.SliceStable(names, func(i, j int) bool {
+		return remainders[names[i]] > remainders[names[j]]
+	})
+	for i := int64(0); i < leftover; i++ {
+		units[names[i]]++
+	}
+	return units
+}
+
+// floorDiv divides rounding towards negative infinity.
+func floorDiv(a, b int64) int64 {
+	quotient := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		quotient--
+	}
+	return quotient
+}
+
+// parseCents parses an amount with up to two decimals into cents. An empty or blank
+// amount is zero.
+func parseCents(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(math.Round(amount * centsPerUnit)), nil
+}
+
+// memberName turns a Splitwise member name into a house name.
+func memberName(column string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(column), "_"))
+}
+
+// indexOfName returns the position of name in names, or -1.
+func indexOfName(names []string, name string) int {
+	for i, candidate := range names {
+		if candidate == name {
+			return i
+		}
+	}
+	return -1
+}
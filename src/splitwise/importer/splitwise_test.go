@@ -0,0 +1,84 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"splitwise/model"
+)
+
+const splitwiseExport = `Date,Description,Category,Cost,Currency,Alice Smith,Bob Jones,Carol
+
+2026-01-02,Groceries,Groceries,30.00,USD,20.00,-10.00,-10.00
+2026-01-03,Pizza,Dining out,10.00,USD,-3.33,6.67,-3.34
+2026-01-04,Internet,Utilities,50.00,USD,-16.67,-16.67,33.34
+2026-01-05,Bob Jones paid Alice Smith,Payment,5.00,USD,-5.00,5.00,0.00
+
+2026-01-06,Total balance, , ,USD,-5.00,-15.00,20.00
+`
+
+func TestReadSplitwise(t *testing.T) {
+	export, err := ReadSplitwise(strings.NewReader(splitwiseExport))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// TEST CASE 1: Member names become house names
+	if strings.Join(export.Members, " ") != "ALICE_SMITH BOB_JONES CAROL" {
+		t.Errorf("Expected the house names, got %v", export.Members)
+	}
+
+	// TEST CASE 2: Every row balances and the rows add up to the totals
+	sums := make(map[string]int64)
+	for _, row := range export.Rows {
+		var total int64
+		for member, effect := range row.Effects {
+			total += effect
+			sums[member] += effect
+		}
+		if total != 0 {
+			t.Errorf("Row %d: expected effects summing to zero, got %v", row.Row, row.Effects)
+		}
+	}
+	for _, member := range export.Members {
+		if sums[member] != export.Totals[member] {
+			t.Errorf("%s: expected rows adding up to %d, got %d", member, export.Totals[member], sums[member])
+		}
+	}
+	if export.Totals["ALICE_SMITH"] != -5 || export.Totals["BOB_JONES"] != -15 || export.Totals["CAROL"] != 20 {
+		t.Errorf("Expected totals -5, -15 and 20, got %v", export.Totals)
+	}
+
+	// TEST CASE 3: A single payer pays the whole cost and payments become CLEAR_DUE
+	groceries := export.Rows[0].Transaction()
+	if groceries.Amount != 30 || groceries.Payers["ALICE_SMITH"] != 30 || groceries.Shares["ALICE_SMITH"] != 10 {
+		t.Errorf("Expected Alice to pay 30 and share 10, got %+v", groceries)
+	}
+	payment := export.Rows[3].Transaction()
+	if payment.Type != model.PAYMENT_TRANSACTION || payment.Payers["BOB_JONES"] != 5 || payment.Shares["ALICE_SMITH"] != 5 {
+		t.Errorf("Expected Bob to pay Alice 5, got %+v", payment)
+	}
+
+	// TEST CASE 4: Expenses keep the category of their row, payments have none
+	if groceries.Category != "Groceries" || payment.Category != "" {
+		t.Errorf("Expected the categories Groceries and none, got %q and %q", groceries.Category, payment.Category)
+	}
+}
+
+func TestReadSplitwiseRefusesInconsistentExports(t *testing.T) {
+	testCases := []struct {
+		name   string
+		export string
+	}{
+		{name: "wrong total", export: strings.Replace(splitwiseExport, "-5.00,-15.00,20.00", "-6.00,-14.00,20.00", 1)},
+		{name: "unbalanced row", export: strings.Replace(splitwiseExport, "20.00,-10.00,-10.00", "20.00,-10.00,-9.00", 1)},
+		{name: "mixed currencies", export: strings.Replace(splitwiseExport, "10.00,USD,-3.33", "10.00,EUR,-3.33", 1)},
+		{name: "missing total", export: splitwiseExport[:strings.Index(splitwiseExport, "2026-01-06")]},
+	}
+
+	for _, tc := range testCases {
+		if _, err := ReadSplitwise(strings.NewReader(tc.export)); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
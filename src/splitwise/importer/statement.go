@@ -0,0 +1,166 @@
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"splitwise/model"
+)
+
+// Status tells what happens to a statement row.
+type Status string
+
+// Statuses of a statement row.
+const (
+	// PROPOSED rows become expenses, and are IMPORTED once committed.
+	PROPOSED Status = "PROPOSED"
+	IMPORTED Status = "IMPORTED"
+	// DUPLICATE rows were imported before.
+	DUPLICATE Status = "DUPLICATE"
+	// SKIPPED rows match a skip rule or are not charges.
+	SKIPPED Status = "SKIPPED"
+)
+
+// Proposal is the expense proposed for one statement row.
+type Proposal struct {
+	// Row is the line of the row in the statement, the header being line 1.
+	Row           int
+	Status        Status
+	Date          time.Time
+	Description   string
+	Category      string
+	Amount        int64
+	Payer         string
+	Beneficiaries []string
+	Split         string
+	// Fingerprint identifies the row across imports of overlapping statements.
+	Fingerprint string
+}
+
+// Spend returns the SPEND command the proposal amounts to, without the command name.
+func (p Proposal) Spend() string {
+	if p.Split != "" {
+		return fmt.Sprintf("%d %s USING %s", p.Amount, p.Payer, p.Split)
+	}
+	return fmt.Sprintf("%d %s", p.Amount, strings.Join(p.Members(), " "))
+}
+
+// Members returns the payer followed by the beneficiaries other than the payer, which is
+// how SPEND lists the members sharing an expense.
+func (p Proposal) Members() []string {
+	members := []string{p.Payer}
+	for _, beneficiary := range p.Beneficiaries {
+		if beneficiary != p.Payer {
+			members = append(members, beneficiary)
+		}
+	}
+	return members
+}
+
+// Note describes the row the proposal comes from, for review output.
+func (p Proposal) Note() string {
+	note := p.Date.Format(model.DateLayout) + " " + p.Description
+	if p.Category != "" {
+		note += " (" + p.Category + ")"
+	}
+	return note
+}
+
+// ReadStatement reads a statement in CSV with a header row and proposes an expense for
+// each row. isImported reports whether a fingerprint was imported before.
+func ReadStatement(r io.Reader, mapping Mapping, isImported func(fingerprint string) bool) ([]Proposal, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading the statement header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	dateColumn, dateOk := columns[mapping.Date]
+	amountColumn, amountOk := columns[mapping.Amount]
+	descriptionColumn, descriptionOk := columns[mapping.Description]
+	if !dateOk || !amountOk || !descriptionOk {
+		return nil, fmt.Errorf("the statement has no %s, %s or %s column", mapping.Date, mapping.Amount, mapping.Description)
+	}
+	dateFormat := mapping.DateFormat
+	if dateFormat == "" {
+		dateFormat = model.DateLayout
+	}
+
+	var proposals []Proposal
+	occurrences := make(map[string]int)
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading the statement: %w", err)
+		}
+		if len(record) <= dateColumn || len(record) <= amountColumn || len(record) <= descriptionColumn {
+			return nil, fmt.Errorf("row %d is missing columns", row)
+		}
+
+		description := strings.TrimSpace(record[descriptionColumn])
+		date, err := time.Parse(dateFormat, strings.TrimSpace(record[dateColumn]))
+		if err != nil {
+			return nil, fmt.Errorf("row %d has an invalid date: %w", row, err)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[amountColumn]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("row %d has an invalid amount: %w", row, err)
+		}
+		if mapping.Negate {
+			value = -value
+		}
+		amount := int64(math.Round(value))
+
+		key := fmt.Sprintf("%s|%d|%s", date.Format(model.DateLayout), amount, description)
+		occurrences[key]++
+		proposal := Proposal{
+			Row:           row,
+			Status:        PROPOSED,
+			Date:          date,
+			Description:   description,
+			Amount:        amount,
+			Payer:         mapping.Payer,
+			Beneficiaries: mapping.Beneficiaries,
+			Fingerprint:   fingerprint(key, occurrences[key]),
+		}
+		rule := mapping.rule(description)
+		proposal.Category = rule.Category
+		if rule.Payer != "" {
+			proposal.Payer = rule.Payer
+		}
+		if len(rule.Beneficiaries) > 0 {
+			proposal.Beneficiaries = rule.Beneficiaries
+		}
+		proposal.Split = rule.Split
+
+		switch {
+		case rule.Skip || amount <= 0:
+			proposal.Status = SKIPPED
+		case isImported(proposal.Fingerprint):
+			proposal.Status = DUPLICATE
+		}
+		proposals = append(proposals, proposal)
+	}
+	return proposals, nil
+}
+
+// fingerprint identifies the n-th row with the same date, amount and description, so that
+// two identical purchases on one day are both imported but never twice.
+func fingerprint(key string, occurrence int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d", key, occurrence)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,75 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadStatement(t *testing.T) {
+	statement := "Posted,Details,Amount\n" +
+		"01/03/2026,\"NETFLIX.COM, LOS GATOS\",-15.99\n" +
+		"01/04/2026,GROCER,-60\n" +
+		"01/04/2026,GROCER,-60\n" +
+		"01/05/2026,CARD PAYMENT,500\n" +
+		"01/06/2026,BARBER,-20\n"
+	mapping := Mapping{
+		Date:          "Posted",
+		Amount:        "Amount",
+		Description:   "Details",
+		DateFormat:    "01/02/2006",
+		Negate:        true,
+		Payer:         "ANDY",
+		Beneficiaries: []string{"ANDY", "WOODY"},
+		Rules: []Rule{
+			{Match: "netflix", Category: "streaming", Split: "ROOMS"},
+			{Match: "BARBER", Skip: true},
+		},
+	}
+
+	// TEST CASE 1: Rows are mapped through the rules
+	proposals, err := ReadStatement(strings.NewReader(statement), mapping, func(string) bool { return false })
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	testCases := []struct {
+		status Status
+		spend  string
+	}{
+		{PROPOSED, "16 ANDY USING ROOMS"},
+		{PROPOSED, "60 ANDY WOODY"},
+		{PROPOSED, "60 ANDY WOODY"},
+		{SKIPPED, "-500 ANDY WOODY"},
+		{SKIPPED, "20 ANDY WOODY"},
+	}
+	if len(proposals) != len(testCases) {
+		t.Fatalf("Expected %d proposals, got %d", len(testCases), len(proposals))
+	}
+	for i, tc := range testCases {
+		if proposals[i].Status != tc.status || proposals[i].Spend() != tc.spend {
+			t.Errorf("Row %d: expected %s %s, got %s %s", proposals[i].Row, tc.status, tc.spend, proposals[i].Status, proposals[i].Spend())
+		}
+	}
+	if proposals[0].Category != "streaming" || proposals[0].Note() != "2026-01-03 NETFLIX.COM, LOS GATOS (streaming)" {
+		t.Errorf("Expected the streaming category, got %q", proposals[0].Note())
+	}
+
+	// TEST CASE 2: Identical rows get different fingerprints
+	if proposals[1].Fingerprint == proposals[2].Fingerprint {
+		t.Errorf("Expected different fingerprints for identical rows")
+	}
+
+	// TEST CASE 3: Rows imported before are duplicates
+	imported := map[string]bool{proposals[1].Fingerprint: true}
+	proposals, _ = ReadStatement(strings.NewReader(statement), mapping, func(fingerprint string) bool { return imported[fingerprint] })
+	if proposals[1].Status != DUPLICATE || proposals[2].Status != PROPOSED {
+		t.Errorf("Expected only the first grocer row to be a duplicate, got %s and %s", proposals[1].Status, proposals[2].Status)
+	}
+}
+
+func TestReadStatementMissingColumn(t *testing.T) {
+	mapping := Mapping{Date: "Date", Amount: "Amount", Description: "Memo", Payer: "ANDY"}
+	_, err := ReadStatement(strings.NewReader("Date,Amount\n2026-01-03,10\n"), mapping, func(string) bool { return false })
+	if err == nil {
+		t.Errorf("Expected an error for a missing column")
+	}
+}
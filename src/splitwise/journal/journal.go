@@ -0,0 +1,226 @@
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	JournalFileName  = "journal.log"
+	SnapshotFileName = "snapshot.json"
+
+	// DefaultSnapshotInterval is the number of records appended between automatic snapshots.
+	DefaultSnapshotInterval = 100
+
+	filePermission = 0o644
+	dirPermission  = 0o755
+)
+
+// snapshotFile wraps a snapshot with the sequence number of the last record it includes.
+type snapshotFile struct {
+	Sequence uint64          `json:"sequence"`
+	State    json.RawMessage `json:"state"`
+}
+
+// Journal is a write-ahead log of records kept in a directory next to the latest snapshot.
+// Each record is written on its own line as "<sequence> <crc32> <payload>" and synced to disk
+// before Append returns, so a crash can at worst leave a torn final line behind.
+type Journal struct {
+	dir              string
+	file             *os.File
+	sequence         uint64
+	snapshotSequence uint64
+	snapshotInterval uint64
+}
+
+// Open opens the journal in dir, creating the directory if needed. Snapshots are taken
+// automatically every snapshotInterval records.
+func Open(dir string, snapshotInterval int) (*Journal, error) {
+	if err := os.MkdirAll(dir, dirPermission); err != nil {
+		return nil, fmt.Errorf("error creating the journal directory: %w", err)
+	}
+	file, err := os.OpenFile(filepath.Join(dir, JournalFileName), os.O_RDWR|os.O_CREATE, filePermission)
+	if err != nil {
+		return nil, fmt.Errorf("error opening the journal: %w", err)
+	}
+	return &Journal{dir: dir, file: file, snapshotInterval: uint64(snapshotInterval)}, nil
+}
+
+// Load returns the latest snapshot, or nil if none was taken, and the payloads of every
+// record appended after it. A torn or corrupt record ends the journal: it is discarded
+// together with anything after it, and the file is truncated so new records follow the
+// last good one.
+func (j *Journal) Load() ([]byte, [][]byte, error) {
+	state, err := j.readSnapshot()
+	if err != nil {
+		return nil, nil, err
+	}
+	j.sequence = j.snapshotSequence
+
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, fmt.Errorf("error reading the journal: %w", err)
+	}
+	var records [][]byte
+	var validLength int64
+	reader := bufio.NewReader(j.file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading the journal: %w", err)
+		}
+		sequence, payload, ok := decodeRecord(line)
+		if !ok || (sequence != j.sequence+1 && sequence > j.snapshotSequence) {
+			break
+		}
+		validLength += int64(len(line))
+		if sequence <= j.snapshotSequence {
+			continue
+		}
+		j.sequence = sequence
+		records = append(records, payload)
+	}
+
+	if err := j.file.Truncate(validLength); err != nil {
+		return nil, nil, fmt.Errorf("error discarding the torn journal tail: %w", err)
+	}
+	if _, err := j.file.Seek(validLength, io.SeekStart); err != nil {
+		return nil, nil, fmt.Errorf("error reading the journal: %w", err)
+	}
+	return state, records, nil
+}
+
+// Append durably writes a record to the end of the journal.
+func (j *Journal) Append(payload []byte) error {
+	if bytes.IndexByte(payload, '\n') >= 0 {
+		return errors.New("journal records cannot contain newlines")
+	}
+	j.sequence++
+	if _, err := j.file.Write(encodeRecord(j.sequence, payload)); err != nil {
+		return fmt.Errorf("error appending to the journal: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("error syncing the journal: %w", err)
+	}
+	return nil
+}
+
+// NeedsCheckpoint reports whether enough records were appended since the last snapshot
+// that a new one should be taken.
+func (j *Journal) NeedsCheckpoint() bool {
+	return j.snapshotInterval > 0 && j.sequence-j.snapshotSequence >= j.snapshotInterval
+}
+
+// Checkpoint durably writes a snapshot of the state covering every record appended so
+// far, then compacts the journal. The snapshot replaces the previous one atomically.
+func (j *Journal) Checkpoint(state []byte) error {
+	data, err := json.Marshal(snapshotFile{Sequence: j.sequence, State: state})
+	if err != nil {
+		return fmt.Errorf("error encoding the snapshot: %w", err)
+	}
+	if err := WriteFileAtomic(filepath.Join(j.dir, SnapshotFileName), data); err != nil {
+		return fmt.Errorf("error writing the snapshot: %w", err)
+	}
+	j.snapshotSequence = j.sequence
+
+	if err := j.file.Truncate(0); err != nil {
+		return fmt.Errorf("error compacting the journal: %w", err)
+	}
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("error compacting the journal: %w", err)
+	}
+	return j.file.Sync()
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
+
+// readSnapshot reads the latest snapshot, if any, and remembers the sequence it covers.
+func (j *Journal) readSnapshot() ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(j.dir, SnapshotFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading the snapshot: %w", err)
+	}
+	var snapshot snapshotFile
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("error decoding the snapshot: %w", err)
+	}
+	j.snapshotSequence = snapshot.Sequence
+	return snapshot.State, nil
+}
+
+// WriteFileAtomic writes data to a temporary file, syncs it and renames it over path,
+// so readers see either the old or the new content and never a partial file. The directory
+// is synced after the rename so the new content survives a crash.
+func WriteFileAtomic(path string, data []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), filePermission); err != nil {
+		return err
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir flushes the entries of a directory to disk.
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return file.Sync()
+}
+
+// encodeRecord frames a payload as a journal line.
+func encodeRecord(sequence uint64, payload []byte) []byte {
+	return []byte(fmt.Sprintf("%d %08x %s\n", sequence, crc32.ChecksumIEEE(payload), payload))
+}
+
+// decodeRecord parses a journal line, reporting false if it is torn or corrupt.
+func decodeRecord(line []byte) (uint64, []byte, bool) {
+	fields := bytes.SplitN(bytes.TrimSuffix(line, []byte("\n")), []byte(" "), 3)
+	if len(fields) != 3 {
+		return 0, nil, false
+	}
+	sequence, err := strconv.ParseUint(string(fields[0]), 10, 64)
+	if err != nil {
+		return 0, nil, false
+	}
+	checksum, err := strconv.ParseUint(string(fields[1]), 16, 32)
+	if err != nil || uint32(checksum) != crc32.ChecksumIEEE(fields[2]) {
+		return 0, nil, false
+	}
+	return sequence, fields[2], true
+}
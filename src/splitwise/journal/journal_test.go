@@ -0,0 +1,118 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAppendAndLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	// TEST CASE 1: Appended records are returned in order after reopening
+	journal, err := Open(dir, DefaultSnapshotInterval)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, _, err := journal.Load(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, payload := range []string{"first", "second"} {
+		if err := journal.Append([]byte(payload)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	journal.Close()
+
+	journal, err = Open(dir, DefaultSnapshotInterval)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer journal.Close()
+	state, records, err := journal.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if state != nil {
+		t.Errorf("Expected no snapshot, got %s", state)
+	}
+	if len(records) != 2 || string(records[0]) != "first" || string(records[1]) != "second" {
+		t.Errorf("Expected records first and second, got %q", records)
+	}
+
+	// TEST CASE 2: Records cannot contain newlines
+	if err := journal.Append([]byte("bad\nrecord")); err == nil {
+		t.Errorf("Expected an error for a record with a newline")
+	}
+}
+
+func TestLoadDiscardsTornRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	journal, _ := Open(dir, DefaultSnapshotInterval)
+	journal.Load()
+	journal.Append([]byte("first"))
+	journal.Close()
+
+	// Simulate a crash halfway through writing the second record
+	file, _ := os.OpenFile(filepath.Join(dir, JournalFileName), os.O_APPEND|os.O_WRONLY, filePermission)
+	file.WriteString("2 0000")
+	file.Close()
+
+	// TEST CASE 1: The torn record is discarded
+	journal, _ = Open(dir, DefaultSnapshotInterval)
+	_, records, err := journal.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(records) != 1 || string(records[0]) != "first" {
+		t.Errorf("Expected only the first record, got %q", records)
+	}
+
+	// TEST CASE 2: New records follow the last good one
+	journal.Append([]byte("second"))
+	journal.Close()
+	journal, _ = Open(dir, DefaultSnapshotInterval)
+	defer journal.Close()
+	_, records, _ = journal.Load()
+	if len(records) != 2 || string(records[1]) != "second" {
+		t.Errorf("Expected records first and second, got %q", records)
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+
+	journal, _ := Open(dir, 2)
+	journal.Load()
+
+	// TEST CASE 1: A checkpoint is needed once the interval is reached
+	journal.Append([]byte("first"))
+	if journal.NeedsCheckpoint() {
+		t.Errorf("Expected no checkpoint after one record")
+	}
+	journal.Append([]byte("second"))
+	if !journal.NeedsCheckpoint() {
+		t.Errorf("Expected a checkpoint after two records")
+	}
+
+	// TEST CASE 2: Records covered by the snapshot are not replayed
+	if err := journal.Checkpoint([]byte(`{"state":2}`)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	journal.Append([]byte("third"))
+	journal.Close()
+
+	journal, _ = Open(dir, 2)
+	defer journal.Close()
+	state, records, err := journal.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(state) != `{"state":2}` {
+		t.Errorf("Expected the snapshot state, got %s", state)
+	}
+	if len(records) != 1 || string(records[0]) != "third" {
+		t.Errorf("Expected only the third record, got %q", records)
+	}
+}
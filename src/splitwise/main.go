@@ -1,21 +1,92 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 
 	"splitwise/cmd"
 )
 
+// exportSubcommand runs a single EXPORT against the stored state instead of an input file.
+const exportSubcommand = "export"
+
 func main() {
-	if len(os.Args) < 2 {
+	if len(os.Args) > 1 && os.Args[1] == exportSubcommand {
+		runExport(os.Args[2:])
+		return
+	}
+
+	journalDir := flag.String("journal", "", "directory of the write-ahead journal used to recover state between runs")
+	stateFile := flag.String("state", "", "state file loaded before the input is processed and saved after it")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
 		fmt.Println("Please provide the input file path")
 		return
 	}
+	if !openState(*journalDir, *stateFile) {
+		return
+	}
+	defer cmd.CloseJournal()
 
-	filePath := os.Args[1]
+	filePath := flag.Arg(0)
 
 	if err := cmd.ProcessFile(filePath); err != nil {
 		fmt.Printf("Error processing file: %v\n", err)
+		return
+	}
+
+	if *stateFile != "" {
+		if err := cmd.SaveState(*stateFile); err != nil {
+			fmt.Printf("Error saving state: %v\n", err)
+		}
+	}
+}
+
+// runExport handles "export [-journal <dir> | -state <path>] <format> <path> [kind]".
+func runExport(arguments []string) {
+	flags := flag.NewFlagSet(exportSubcommand, flag.ExitOnError)
+	journalDir := flags.String("journal", "", "directory of the write-ahead journal to export from")
+	stateFile := flags.String("state", "", "state file to export from")
+	flags.Parse(arguments)
+
+	if !openState(*journalDir, *stateFile) {
+		return
+	}
+	defer cmd.CloseJournal()
+
+	if err := cmd.Export(flags.Args()); err != nil {
+		fmt.Printf("Error exporting: %v\n", err)
+	}
+}
+
+// openState recovers the house from the journal or the state file, if either is given.
+// It reports false after printing the error when the state cannot be recovered.
+func openState(journalDir, stateFile string) bool {
+	if journalDir != "" && stateFile != "" {
+		fmt.Println("Please use either -journal or -state")
+		return false
+	}
+
+	if journalDir != "" {
+		if err := cmd.OpenJournal(journalDir); err != nil {
+			fmt.Printf("Error opening journal: %v\n", err)
+			return false
+		}
+	}
+
+	if stateFile != "" {
+		if _, err := os.Stat(stateFile); err == nil {
+			if err := cmd.LoadState(stateFile); err != nil {
+				fmt.Printf("Error loading state: %v\n", err)
+				return false
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("Error loading state: %v\n", err)
+			return false
+		}
 	}
+	return true
 }
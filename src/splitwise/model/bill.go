@@ -0,0 +1,36 @@
+package model
+
+// BillLineKind identifies what a line of an itemized bill stands for.
+type BillLineKind string
+
+// Kinds of lines accepted inside a BILL block.
+const (
+	ITEM_LINE     BillLineKind = "ITEM"
+	TAX_LINE      BillLineKind = "TAX"
+	TIP_LINE      BillLineKind = "TIP"
+	DISCOUNT_LINE BillLineKind = "DISCOUNT"
+)
+
+// BillError is a custom error type for itemized bill errors.
+type BillError string
+
+// Error messages related to itemized bills.
+const (
+	INVALID_BILL = BillError("INVALID_BILL")
+)
+
+// BillLine is a single line of an itemized bill. Items list the housemates who shared
+// them; tax, tip and discount lines are apportioned over everyone on the bill.
+// Shares is filled in once the line has been split.
+type BillLine struct {
+	Kind         BillLineKind     `json:"kind"`
+	Amount       int64            `json:"amount"`
+	Participants []string         `json:"participants,omitempty"`
+	Shares       map[string]int64 `json:"shares"`
+}
+
+// Bill is an itemized expense paid by a single housemate.
+type Bill struct {
+	Payer string
+	Lines []BillLine
+}
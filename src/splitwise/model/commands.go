@@ -9,12 +9,78 @@ const (
 	SPEND      CommandType = "SPEND"
 	DUES       CommandType = "DUES"
 	CLEAR_DUES CommandType = "CLEAR_DUE"
+
+	SPEND_MULTI CommandType = "SPEND_MULTI"
+	LEND        CommandType = "LEND"
+	PAY_FOR     CommandType = "PAY_FOR"
+	HISTORY     CommandType = "HISTORY"
+
+	REFUND         CommandType = "REFUND"
+	REFUND_EXPENSE CommandType = "REFUND_EXPENSE"
+	SET            CommandType = "SET"
+	PAY            CommandType = "PAY"
+	FORMER         CommandType = "FORMER"
+	DEPOSITS       CommandType = "DEPOSITS"
+
+	KITTY_TOPUP   CommandType = "KITTY_TOPUP"
+	KITTY_SPEND   CommandType = "KITTY_SPEND"
+	KITTY_BALANCE CommandType = "KITTY_BALANCE"
+	SETTLE_UP     CommandType = "SETTLE_UP"
+
+	DEFINE_SPLIT CommandType = "DEFINE_SPLIT"
+	DATE         CommandType = "DATE"
+	AWAY         CommandType = "AWAY"
+	SPEND_PERIOD CommandType = "SPEND_PERIOD"
+
+	BILL     CommandType = "BILL"
+	END_BILL CommandType = "END_BILL"
+
+	CHECK     CommandType = "CHECK"
+	EXPLAIN   CommandType = "EXPLAIN"
+	GRAPH     CommandType = "GRAPH"
+	STATEMENT CommandType = "STATEMENT"
+
+	CLOSE_PERIOD  CommandType = "CLOSE_PERIOD"
+	REOPEN_PERIOD CommandType = "REOPEN_PERIOD"
+	AUDIT         CommandType = "AUDIT"
+
+	CHECKPOINT CommandType = "CHECKPOINT"
+	SAVE       CommandType = "SAVE"
+	LOAD       CommandType = "LOAD"
+	MIGRATE    CommandType = "MIGRATE"
+	EXPORT     CommandType = "EXPORT"
+	IMPORT     CommandType = "IMPORT"
 )
 
+// readOnlyCommands lists the commands that never change the state of the house.
+var readOnlyCommands = map[CommandType]bool{
+	DUES:          true,
+	HISTORY:       true,
+	FORMER:        true,
+	DEPOSITS:      true,
+	KITTY_BALANCE: true,
+	CHECK:         true,
+	EXPLAIN:       true,
+	GRAPH:         true,
+	STATEMENT:     true,
+	AUDIT:         true,
+	CHECKPOINT:    true,
+	SAVE:          true,
+	MIGRATE:       true,
+	EXPORT:        true,
+}
+
+// IsMutating reports whether executing the command may change the state of the house.
+func (c CommandType) IsMutating() bool {
+	return !readOnlyCommands[c]
+}
+
 // Command represents an action with a specific CommandType and associated arguments.
+// Body holds the fields of every line inside a multi-line block such as BILL.
 type Command struct {
-	CommandType CommandType
-	Arguments   []string
+	CommandType CommandType `json:"command"`
+	Arguments   []string    `json:"arguments,omitempty"`
+	Body        [][]string  `json:"body,omitempty"`
 }
 
 // CommandError defines a type for errors related to command execution.
@@ -22,7 +88,9 @@ type CommandError string
 
 // Error messages related to command execution.
 const (
-	FAILURE CommandError = "FAILURE"
+	FAILURE    CommandError = "FAILURE"
+	NO_JOURNAL CommandError = "NO_JOURNAL"
+	DRY_RUN    CommandError = "DRY_RUN"
 )
 
 type CommandSuccess string
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// SimplificationStep is one settlement chosen while simplifying the dues: the housemate
+// with the lowest balance pays the one with the highest, and the smaller of the two
+// balances is settled. The balances are the ones before the step.
+type SimplificationStep struct {
+	Debtor          string `json:"debtor"`
+	Creditor        string `json:"creditor"`
+	Amount          int64  `json:"amount"`
+	DebtorBalance   int64  `json:"debtor_balance"`
+	CreditorBalance int64  `json:"creditor_balance"`
+}
+
+// Contribution is a transaction of the history that moved the balance of either side of
+// an explained due. Effects holds what it did to each of the two sides.
+type Contribution struct {
+	ID      int              `json:"id"`
+	Date    time.Time        `json:"date"`
+	Type    TransactionType  `json:"type"`
+	Amount  int64            `json:"amount"`
+	Effects map[string]int64 `json:"effects"`
+}
+
+// Explanation traces how the simplified due From owes To was derived. RawDue is what the
+// raw dues say From owes To before simplification.
+type Explanation struct {
+	From          string               `json:"from"`
+	To            string               `json:"to"`
+	Due           int64                `json:"due"`
+	RawDue        int64                `json:"raw_due"`
+	Contributions []Contribution       `json:"contributions"`
+	Balances      map[string]int64     `json:"balances"`
+	Steps         []SimplificationStep `json:"steps"`
+}
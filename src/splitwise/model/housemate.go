@@ -10,4 +10,15 @@ const (
 	MEMBER_ALREADY_EXISTS = HousemateError("MEMBER_ALREADY_EXISTS")
 	MEMBER_NOT_FOUND      = HousemateError("MEMBER_NOT_FOUND")
 	HOUSEFUL              = HousemateError("HOUSEFUL")
+	INVALID_DEPOSIT       = HousemateError("INVALID_DEPOSIT")
+	RESERVED_NAME         = HousemateError("RESERVED_NAME")
+
+	// KITTY is the name of the shared house pot; no housemate can use it.
+	KITTY = "KITTY"
 )
+
+// Deposit is a security deposit a housemate paid to the holder when moving in.
+type Deposit struct {
+	Holder string `json:"holder"`
+	Amount int64  `json:"amount"`
+}
@@ -0,0 +1,98 @@
+package model
+
+import "sort"
+
+// Posting moves an amount into a member's account. A positive amount is owed to the
+// member and a negative amount is owed by them.
+type Posting struct {
+	Account string `json:"account"`
+	Amount  int64  `json:"amount"`
+}
+
+// Entry is a balanced set of postings, made for the transaction with TransactionID.
+type Entry struct {
+	TransactionID int       `json:"transaction_id,omitempty"`
+	Postings      []Posting `json:"postings"`
+}
+
+// LedgerError is a custom error type for errors posting to the member accounts.
+type LedgerError string
+
+// Error messages related to the member accounts.
+const (
+	UNBALANCED_POSTING = LedgerError("UNBALANCED_POSTING")
+)
+
+// Effects returns what the transaction does to each member's account: what they paid
+// minus their share.
+func (t Transaction) Effects() map[string]int64 {
+	effects := make(map[string]int64)
+	for member, paid := range t.Payers {
+		effects[member] += paid
+	}
+	for member, share := range t.Shares {
+		effects[member] -= share
+	}
+	return effects
+}
+
+// NewEntry builds the entry posting the effects, leaving out zero amounts. Postings are
+// sorted by account.
+func NewEntry(transactionID int, effects map[string]int64) Entry {
+	entry := Entry{TransactionID: transactionID, Postings: []Posting{}}
+	for account, amount := range effects {
+		if amount != ZERO_DUE {
+			entry.Postings = append(entry.Postings, Posting{Account: account, Amount: amount})
+		}
+	}
+	sort.Slice(entry.Postings, func(i, j int) bool {
+		return entry.Postings[i].Account < entry.Postings[j].Account
+	})
+	return entry
+}
+
+// IsBalanced reports whether the postings of the entry sum to zero.
+func (e Entry) IsBalanced() bool {
+	var sum int64
+	for _, posting := range e.Postings {
+		sum += posting.Amount
+	}
+	return sum == ZERO_DUE
+}
+
+// PairEffects splits effects that sum to zero into the dues they create: every member with
+// a negative effect owes the members with a positive effect, paired greedily in name order.
+func PairEffects(effects map[string]int64) []Due {
+	var creditors, debtors []string
+	remaining := make(map[string]int64, len(effects))
+	for name, effect := range effects {
+		if effect > 0 {
+			creditors = append(creditors, name)
+		} else if effect < 0 {
+			debtors = append(debtors, name)
+		}
+		remaining[name] = effect
+	}
+	sort.Strings(creditors)
+	sort.Strings(debtors)
+
+	var dues []Due
+	i, j := 0, 0
+	for i < len(creditors) && j < len(debtors) {
+		creditor, debtor := creditors[i], debtors[j]
+		amount := remaining[creditor]
+		if -remaining[debtor] < amount {
+			amount = -remaining[debtor]
+		}
+		dues = append(dues, Due{From: debtor, To: creditor, Amount: amount})
+		remaining[creditor] -= amount
+		remaining[debtor] += amount
+		if remaining[creditor] == ZERO_DUE {
+			i++
+		}
+		if remaining[debtor] == ZERO_DUE {
+			j++
+		}
+	}
+	return dues
+}
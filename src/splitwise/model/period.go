@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// DateLayout is the format of every date accepted or printed by the commands.
+const DateLayout = "2006-01-02"
+
+// MonthLayout is the format of the months accepted by the period commands and statements.
+const MonthLayout = "2006-01"
+
+// Interval is a range of days between From and To. A zero From or To leaves that end open.
+type Interval struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// PeriodError is a custom error type for date and period errors.
+type PeriodError string
+
+// Error messages related to dates and periods.
+const (
+	INVALID_PERIOD    = PeriodError("INVALID_PERIOD")
+	PERIOD_CLOSED     = PeriodError("PERIOD_CLOSED")
+	PERIOD_NOT_CLOSED = PeriodError("PERIOD_NOT_CLOSED")
+)
+
+// ClosedPeriod is a month locked by CLOSE_PERIOD. Balances holds the net balance of every
+// member at the end of the month, its closing position.
+type ClosedPeriod struct {
+	Month    string           `json:"month"`
+	Balances map[string]int64 `json:"balances"`
+}
+
+// AuditRecord is an entry of the audit log: a period closed or reopened on the house date,
+// with the reason given for reopening it.
+type AuditRecord struct {
+	Date   time.Time   `json:"date"`
+	Action CommandType `json:"action"`
+	Month  string      `json:"month"`
+	Reason string      `json:"reason,omitempty"`
+}
@@ -0,0 +1,45 @@
+package model
+
+// Setting names a house-wide option that changes how commands behave.
+type Setting string
+
+// Settings that can be changed with the SET command.
+const (
+	OVERPAYMENT   Setting = "OVERPAYMENT"
+	CHECK_SETTING Setting = "CHECK"
+)
+
+// Values accepted by the OVERPAYMENT setting.
+const (
+	OVERPAYMENT_REJECT = "REJECT"
+	OVERPAYMENT_CREDIT = "CREDIT"
+)
+
+// Values accepted by the CHECK setting. With ON the invariants are checked after every
+// command that changes the house.
+const (
+	CHECK_OFF = "OFF"
+	CHECK_ON  = "ON"
+)
+
+// SettingError is a custom error type for setting-related errors.
+type SettingError string
+
+// Error messages related to settings.
+const (
+	INVALID_SETTING = SettingError("INVALID_SETTING")
+)
+
+// DefaultSettings returns the value every setting takes before it is changed.
+func DefaultSettings() map[Setting]string {
+	return map[Setting]string{
+		OVERPAYMENT:   OVERPAYMENT_REJECT,
+		CHECK_SETTING: CHECK_OFF,
+	}
+}
+
+// AllowedSettingValues lists the values each setting accepts.
+var AllowedSettingValues = map[Setting][]string{
+	OVERPAYMENT:   {OVERPAYMENT_REJECT, OVERPAYMENT_CREDIT},
+	CHECK_SETTING: {CHECK_OFF, CHECK_ON},
+}
@@ -0,0 +1,9 @@
+package model
+
+// StateError is a custom error type for errors reading or writing state files.
+type StateError string
+
+// Error messages related to state files.
+const (
+	INVALID_STATE = StateError("INVALID_STATE")
+)
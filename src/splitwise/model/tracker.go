@@ -4,5 +4,31 @@ type TrackerError string
 
 // Constants for error messages.
 const (
-	INCORRECT_PAYMENT = HousemateError("INCORRECT_PAYMENT")
+	INCORRECT_PAYMENT     = HousemateError("INCORRECT_PAYMENT")
+	AMOUNT_MISMATCH       = TrackerError("AMOUNT_MISMATCH")
+	TRANSACTION_NOT_FOUND = TrackerError("TRANSACTION_NOT_FOUND")
+	INVALID_REFUND        = TrackerError("INVALID_REFUND")
+	INVALID_TRANSFER      = TrackerError("INVALID_TRANSFER")
+	INSUFFICIENT_KITTY    = TrackerError("INSUFFICIENT_KITTY")
+	INVALID_SPLIT         = TrackerError("INVALID_SPLIT")
+	SPLIT_NOT_FOUND       = TrackerError("SPLIT_NOT_FOUND")
+	BALANCE_MISMATCH      = TrackerError("BALANCE_MISMATCH")
+	INVALID_AMOUNT        = TrackerError("INVALID_AMOUNT")
 )
+
+// AllocationStrategy decides how a lump-sum PAY is spread over a member's creditors.
+type AllocationStrategy string
+
+// Allocation strategies accepted by the PAY command.
+const (
+	LARGEST_FIRST AllocationStrategy = "LARGEST_FIRST"
+	OLDEST_FIRST  AllocationStrategy = "OLDEST_FIRST"
+	PROPORTIONAL  AllocationStrategy = "PROPORTIONAL"
+)
+
+// Due is a single amount one housemate owes another.
+type Due struct {
+	From   string
+	To     string
+	Amount int64
+}
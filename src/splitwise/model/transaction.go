@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// TransactionType identifies the kind of entry recorded in the house history.
+type TransactionType string
+
+// Transaction types that are recorded in the history.
+const (
+	EXPENSE_TRANSACTION       TransactionType = "SPEND"
+	MULTI_EXPENSE_TRANSACTION TransactionType = "SPEND_MULTI"
+	LOAN_TRANSACTION          TransactionType = "LEND"
+	PAID_FOR_TRANSACTION      TransactionType = "PAY_FOR"
+	PAYMENT_TRANSACTION       TransactionType = "CLEAR_DUE"
+	REFUND_TRANSACTION        TransactionType = "REFUND"
+	TRANSFER_TRANSACTION      TransactionType = "TRANSFER"
+	DEPOSIT_TRANSACTION       TransactionType = "DEPOSIT"
+	DEPOSIT_RELEASE           TransactionType = "DEPOSIT_RELEASE"
+	KITTY_TOPUP_TRANSACTION   TransactionType = "KITTY_TOPUP"
+	KITTY_SPEND_TRANSACTION   TransactionType = "KITTY_SPEND"
+	KITTY_PAYOUT_TRANSACTION  TransactionType = "KITTY_PAYOUT"
+	KITTY_SETTLE_TRANSACTION  TransactionType = "KITTY_SETTLE"
+	BILL_TRANSACTION          TransactionType = "BILL"
+	PERIOD_TRANSACTION        TransactionType = "SPEND_PERIOD"
+)
+
+// informationalTransactions lists the transaction types kept for the record only. They move
+// money into or out of a deposit or the kitty and never change the dues between housemates.
+var informationalTransactions = map[TransactionType]bool{
+	DEPOSIT_TRANSACTION:      true,
+	KITTY_TOPUP_TRANSACTION:  true,
+	KITTY_SPEND_TRANSACTION:  true,
+	KITTY_PAYOUT_TRANSACTION: true,
+}
+
+// AffectsDues reports whether transactions of this type change the dues between housemates.
+func (t TransactionType) AffectsDues() bool {
+	return !informationalTransactions[t]
+}
+
+// Transaction is a single entry in the house history.
+// Payers maps each housemate to the amount credited to them (money they paid out)
+// and Shares maps each housemate to the amount debited to them (value they
+// received); both add up to Amount. RefundOf is the ID of the transaction a
+// refund reverses, or zero. Items holds the breakdown of an itemized bill.
+// Date is the house date when the transaction was recorded, or zero if none was set.
+// Split and Weights name the split profile and the weights used to share an expense.
+// Category is the spending category an import gave the expense, such as Groceries, or empty.
+type Transaction struct {
+	ID       int              `json:"id"`
+	Date     time.Time        `json:"date"`
+	Type     TransactionType  `json:"type"`
+	Amount   int64            `json:"amount"`
+	Payers   map[string]int64 `json:"payers"`
+	Shares   map[string]int64 `json:"shares"`
+	RefundOf int              `json:"refund_of,omitempty"`
+	Items    []BillLine       `json:"items,omitempty"`
+	Split    string           `json:"split,omitempty"`
+	Weights  map[string]int64 `json:"weights,omitempty"`
+	Category string           `json:"category,omitempty"`
+}
@@ -0,0 +1,117 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"splitwise/model"
+)
+
+// schemaVersionField is the name of the field holding the schema version of a document.
+const schemaVersionField = "schema_version"
+
+// migration upgrades a decoded state document from one schema version to the next.
+type migration struct {
+	description string
+	apply       func(document map[string]json.RawMessage) error
+}
+
+// migrations[n] upgrades a document from version n to version n+1.
+var migrations = []migration{
+	{
+		// Version 0 is the snapshot written by journal checkpoints before state files
+		// existed. It has the same fields as version 1 but no schema version.
+		description: "add the schema version to an unversioned snapshot",
+		apply: func(document map[string]json.RawMessage) error {
+			return nil
+		},
+	},
+	{
+		description: "add the fingerprints of imported statement rows",
+		apply: func(document map[string]json.RawMessage) error {
+			document["imports"] = json.RawMessage("[]")
+			return nil
+		},
+	},
+	{
+		description: "post every transaction that changed the dues to the member accounts",
+		apply: func(document map[string]json.RawMessage) error {
+			var history []model.Transaction
+			if raw, ok := document["history"]; ok {
+				if err := json.Unmarshal(raw, &history); err != nil {
+					return err
+				}
+			}
+			entries := []model.Entry{}
+			for _, transaction := range history {
+				if !transaction.Type.AffectsDues() {
+					continue
+				}
+				if entry := model.NewEntry(transaction.ID, transaction.Effects()); len(entry.Postings) > 0 {
+					entries = append(entries, entry)
+				}
+			}
+			raw, err := json.Marshal(entries)
+			if err != nil {
+				return err
+			}
+			document["entries"] = raw
+			return nil
+		},
+	},
+	{
+		description: "add the closed periods and the audit log",
+		apply: func(document map[string]json.RawMessage) error {
+			document["closed_periods"] = json.RawMessage("[]")
+			document["audit"] = json.RawMessage("[]")
+			return nil
+		},
+	},
+	{
+		// Transactions recorded before categories existed have none, and their expenses
+		// keep the category of their type.
+		description: "add the category of imported expenses",
+		apply: func(document map[string]json.RawMessage) error {
+			return nil
+		},
+	},
+}
+
+// Migrate upgrades a state document to the current schema version. It returns the upgraded
+// document and a description of every migration applied, oldest first. A document without
+// a schema version is taken to be version 0.
+func Migrate(data []byte) ([]byte, []string, error) {
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, nil, invalidState("corrupt state file: %v", err)
+	}
+	if document == nil {
+		return nil, nil, invalidState("corrupt state file: not a JSON object")
+	}
+
+	version := 0
+	if raw, ok := document[schemaVersionField]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, nil, invalidState("corrupt schema version: %v", err)
+		}
+	}
+	if version < 0 || version > SchemaVersion {
+		return nil, nil, invalidState("unsupported schema version %d", version)
+	}
+
+	var applied []string
+	for ; version < SchemaVersion; version++ {
+		step := migrations[version]
+		if err := step.apply(document); err != nil {
+			return nil, nil, invalidState("migrating from version %d: %v", version, err)
+		}
+		document[schemaVersionField] = json.RawMessage(fmt.Sprint(version + 1))
+		applied = append(applied, fmt.Sprintf("%d TO %d %s", version, version+1, step.description))
+	}
+
+	upgraded, err := json.Marshal(document)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error encoding the state: %w", err)
+	}
+	return upgraded, applied, nil
+}
@@ -0,0 +1,53 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMigrationChain(t *testing.T) {
+	if len(migrations) != SchemaVersion {
+		t.Fatalf("Expected %d migrations, got %d", SchemaVersion, len(migrations))
+	}
+}
+
+func TestLoadFixturesOfEveryVersion(t *testing.T) {
+	current, err := Load(filepath.Join("testdata", fmt.Sprintf("v%d.json", SchemaVersion)))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for version := 0; version <= SchemaVersion; version++ {
+		path := filepath.Join("testdata", fmt.Sprintf("v%d.json", version))
+
+		// TEST CASE 1: Every past version loads into the same state
+		snapshot, err := Load(path)
+		if err != nil {
+			t.Errorf("v%d: expected no error, got %v", version, err)
+			continue
+		}
+		if !reflect.DeepEqual(snapshot, current) {
+			t.Errorf("v%d: expected %+v, got %+v", version, current, snapshot)
+		}
+
+		// TEST CASE 2: One migration is reported per version upgraded
+		data, _ := os.ReadFile(path)
+		_, applied, err := Migrate(data)
+		if err != nil {
+			t.Errorf("v%d: expected no error, got %v", version, err)
+		}
+		if len(applied) != SchemaVersion-version {
+			t.Errorf("v%d: expected %d migrations, got %v", version, SchemaVersion-version, applied)
+		}
+	}
+}
+
+func TestMigrateRefusesNewerVersions(t *testing.T) {
+	_, _, err := Migrate([]byte(fmt.Sprintf(`{"schema_version": %d}`, SchemaVersion+1)))
+	if err == nil {
+		t.Errorf("Expected an error for a newer schema version")
+	}
+}
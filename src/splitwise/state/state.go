@@ -0,0 +1,237 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"splitwise/global"
+	"splitwise/journal"
+	"splitwise/model"
+)
+
+// SchemaVersion is the version of the state file format written by Save. Every change to
+// the format bumps it and adds the migration from the previous version to migrations.
+const SchemaVersion = 5
+
+// Document is the JSON layout of a state file. Besides the schema version it holds:
+//   - housemates and former: the current and former members of the house
+//   - dues: raw dues as creditor -> debtor -> amount
+//   - simplified_dues: simplified dues as debtor -> creditor -> amount
+//   - history: every recorded transaction, in order, with the category of imported expenses
+//   - imports: fingerprints of the statement rows already imported
+//   - entries: the balanced postings to the member accounts, in order
+//   - closed_periods: the locked months with their closing balances, sorted by month
+//   - audit: the audit log of closed and reopened periods, in order
+//   - settings, deposits, kitty, today, stays, aways and splits: the remaining house state
+type Document struct {
+	SchemaVersion int `json:"schema_version"`
+	global.Snapshot
+}
+
+// Encode returns the snapshot as a state document of the current schema version.
+func Encode(snapshot global.Snapshot) ([]byte, error) {
+	data, err := json.MarshalIndent(Document{SchemaVersion: SchemaVersion, Snapshot: snapshot}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding the state: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// Decode upgrades a state document of any supported schema version and validates it.
+// Nothing is returned unless the whole document is valid.
+func Decode(data []byte) (global.Snapshot, error) {
+	upgraded, _, err := Migrate(data)
+	if err != nil {
+		return global.Snapshot{}, err
+	}
+	var document Document
+	if err := json.Unmarshal(upgraded, &document); err != nil {
+		return global.Snapshot{}, invalidState("corrupt state file: %v", err)
+	}
+	if err := Validate(document.Snapshot); err != nil {
+		return global.Snapshot{}, err
+	}
+	return document.Snapshot, nil
+}
+
+// Save writes the snapshot to path as a state file. The file is replaced atomically.
+func Save(path string, snapshot global.Snapshot) error {
+	data, err := Encode(snapshot)
+	if err != nil {
+		return err
+	}
+	if err := journal.WriteFileAtomic(path, data); err != nil {
+		return fmt.Errorf("error writing the state file: %w", err)
+	}
+	return nil
+}
+
+// Load reads, upgrades and validates the state file at path.
+func Load(path string) (global.Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return global.Snapshot{}, fmt.Errorf("error reading the state file: %w", err)
+	}
+	return Decode(data)
+}
+
+// Validate checks that the snapshot describes a consistent house: every member is known,
+// no due is negative, every entry balances, and the member accounts, the raw dues and the
+// simplified dues give every member the same balance, with the balances summing to zero.
+func Validate(snapshot global.Snapshot) error {
+	housemates := make(map[string]bool)
+	for _, housemate := range snapshot.Housemates {
+		if housemate == "" || housemate == model.KITTY || housemates[housemate] {
+			return invalidState("invalid housemate %q", housemate)
+		}
+		housemates[housemate] = true
+	}
+	if len(housemates) > model.MAX_HOUSEMATES {
+		return invalidState("%d housemates exceed the capacity of %d", len(housemates), model.MAX_HOUSEMATES)
+	}
+	members := make(map[string]bool)
+	for housemate := range housemates {
+		members[housemate] = true
+	}
+	for _, housemate := range snapshot.Former {
+		if housemate == "" || members[housemate] {
+			return invalidState("invalid former housemate %q", housemate)
+		}
+		members[housemate] = true
+	}
+
+	rawBalances, err := dueBalances("dues", snapshot.Dues, housemates)
+	if err != nil {
+		return err
+	}
+	simplifiedBalances, err := dueBalances("simplified_dues", snapshot.SimplifiedDues, housemates)
+	if err != nil {
+		return err
+	}
+	for _, housemate := range snapshot.Housemates {
+		// simplified dues are keyed by debtor, so their balances have the opposite sign
+		if rawBalances[housemate] != -simplifiedBalances[housemate] {
+			return invalidState("raw and simplified dues disagree on the balance of %s", housemate)
+		}
+	}
+
+	accounts := make(map[string]int64)
+	for _, entry := range snapshot.Entries {
+		if !entry.IsBalanced() {
+			return invalidState("entry of transaction %d does not balance", entry.TransactionID)
+		}
+		for _, posting := range entry.Postings {
+			if !members[posting.Account] {
+				return invalidState("posting to unknown member %s", posting.Account)
+			}
+			accounts[posting.Account] += posting.Amount
+		}
+	}
+	for member := range members {
+		if housemates[member] && accounts[member] != rawBalances[member] {
+			return invalidState("the account of %s disagrees with the dues", member)
+		}
+		if !housemates[member] && accounts[member] != model.ZERO_DUE {
+			return invalidState("former housemate %s has a balance of %d", member, accounts[member])
+		}
+	}
+
+	for setting, value := range snapshot.Settings {
+		if !isAllowedSetting(setting, value) {
+			return invalidState("invalid value %q for setting %s", value, setting)
+		}
+	}
+	for member, deposit := range snapshot.Deposits {
+		if !housemates[member] || !housemates[deposit.Holder] || member == deposit.Holder || deposit.Amount <= 0 {
+			return invalidState("invalid deposit of %s", member)
+		}
+	}
+	for member := range snapshot.Kitty {
+		if !housemates[member] {
+			return invalidState("kitty share of unknown housemate %s", member)
+		}
+	}
+	for _, intervals := range []map[string][]model.Interval{snapshot.Stays, snapshot.Aways} {
+		for member := range intervals {
+			if !members[member] {
+				return invalidState("dates of unknown member %s", member)
+			}
+		}
+	}
+	for _, fingerprint := range snapshot.Imports {
+		if fingerprint == "" {
+			return invalidState("empty import fingerprint")
+		}
+	}
+	months := make(map[string]bool)
+	for _, period := range snapshot.ClosedPeriods {
+		if _, err := time.Parse(model.MonthLayout, period.Month); err != nil || months[period.Month] {
+			return invalidState("invalid closed period %q", period.Month)
+		}
+		months[period.Month] = true
+		for member := range period.Balances {
+			if !members[member] {
+				return invalidState("closing balance of unknown member %s", member)
+			}
+		}
+	}
+	for _, record := range snapshot.Audit {
+		if _, err := time.Parse(model.MonthLayout, record.Month); err != nil ||
+			(record.Action != model.CLOSE_PERIOD && record.Action != model.REOPEN_PERIOD) {
+			return invalidState("invalid audit record %s %q", record.Action, record.Month)
+		}
+	}
+	for i, transaction := range snapshot.History {
+		if transaction.ID != i+1 {
+			return invalidState("transaction %d is out of order", transaction.ID)
+		}
+	}
+	return nil
+}
+
+// dueBalances returns the net balance of every housemate in a map of dues, keyed the way
+// the dues are, after checking that every due is non-negative and between housemates.
+func dueBalances(name string, dues map[string]map[string]int64, housemates map[string]bool) (map[string]int64, error) {
+	balances := make(map[string]int64)
+	var total int64
+	for outer, inner := range dues {
+		if !housemates[outer] {
+			return nil, invalidState("%s of unknown housemate %s", name, outer)
+		}
+		for key, amount := range inner {
+			if !housemates[key] {
+				return nil, invalidState("%s of unknown housemate %s", name, key)
+			}
+			if amount < 0 || (amount > 0 && outer == key) {
+				return nil, invalidState("invalid %s %d between %s and %s", name, amount, outer, key)
+			}
+			balances[outer] += amount
+			balances[key] -= amount
+		}
+	}
+	for _, balance := range balances {
+		total += balance
+	}
+	if total != model.ZERO_DUE {
+		return nil, invalidState("%s balances sum to %d instead of zero", name, total)
+	}
+	return balances, nil
+}
+
+// isAllowedSetting reports whether value is accepted by the setting.
+func isAllowedSetting(setting model.Setting, value string) bool {
+	for _, allowed := range model.AllowedSettingValues[setting] {
+		if allowed == value {
+			return true
+		}
+	}
+	return false
+}
+
+// invalidState builds an INVALID_STATE error explaining what is wrong with the file.
+func invalidState(format string, args ...interface{}) error {
+	return errors.New(string(model.INVALID_STATE) + ": " + fmt.Sprintf(format, args...))
+}
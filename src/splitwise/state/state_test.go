@@ -0,0 +1,109 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"splitwise/global"
+	"splitwise/model"
+)
+
+// newSnapshot returns the snapshot of a house where Woody owes Andy 700.
+func newSnapshot() global.Snapshot {
+	storage := global.NewGlobalMapStorage()
+	storage.AddHousemate("Andy")
+	storage.AddHousemate("Woody")
+	storage.PostTransaction(model.Transaction{
+		Type:   model.LOAN_TRANSACTION,
+		Amount: 700,
+		Payers: map[string]int64{"Andy": 700},
+		Shares: map[string]int64{"Woody": 700},
+	})
+	return storage.Snapshot()
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	// TEST CASE 1: A saved state loads back unchanged
+	if err := Save(path, newSnapshot()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	snapshot, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(snapshot.Housemates) != 2 || snapshot.SimplifiedDues["Woody"]["Andy"] != 700 || len(snapshot.History) != 1 {
+		t.Errorf("Expected the saved state, got %+v", snapshot)
+	}
+
+	// TEST CASE 2: The schema version is written to the file
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), fmt.Sprintf(`"schema_version": %d`, SchemaVersion)) {
+		t.Errorf("Expected a schema version, got %s", data)
+	}
+}
+
+func TestLoadRefusesInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	testCases := []struct {
+		name    string
+		content string
+		edit    func(snapshot *global.Snapshot)
+	}{
+		{name: "corrupt", content: `{"schema_version": 1, "housemates": [`},
+		{name: "unknown version", content: `{"schema_version": 99}`},
+		{name: "unknown member", edit: func(snapshot *global.Snapshot) {
+			snapshot.Dues["Buzz"] = map[string]int64{"Andy": 100}
+		}},
+		{name: "former housemate still living in", edit: func(snapshot *global.Snapshot) {
+			snapshot.Former = []string{"Andy"}
+		}},
+		{name: "negative due", edit: func(snapshot *global.Snapshot) {
+			snapshot.Dues["Andy"]["Woody"] = -700
+		}},
+		{name: "balances disagree", edit: func(snapshot *global.Snapshot) {
+			snapshot.SimplifiedDues["Woody"]["Andy"] = 500
+		}},
+		{name: "unbalanced entry", edit: func(snapshot *global.Snapshot) {
+			snapshot.Entries[0].Postings[0].Amount++
+		}},
+		{name: "accounts disagree with dues", edit: func(snapshot *global.Snapshot) {
+			snapshot.Entries = nil
+		}},
+		{name: "unknown setting value", edit: func(snapshot *global.Snapshot) {
+			snapshot.Settings[model.OVERPAYMENT] = "MAYBE"
+		}},
+		{name: "invalid closed period", edit: func(snapshot *global.Snapshot) {
+			snapshot.ClosedPeriods = []model.ClosedPeriod{{Month: "2026-13"}}
+		}},
+		{name: "closing balance of unknown member", edit: func(snapshot *global.Snapshot) {
+			snapshot.ClosedPeriods = []model.ClosedPeriod{{Month: "2026-01", Balances: map[string]int64{"Buzz": 0}}}
+		}},
+		{name: "invalid audit record", edit: func(snapshot *global.Snapshot) {
+			snapshot.Audit = []model.AuditRecord{{Action: model.SPEND, Month: "2026-01"}}
+		}},
+	}
+
+	for _, tc := range testCases {
+		path := filepath.Join(dir, "state.json")
+		if tc.edit != nil {
+			snapshot := newSnapshot()
+			tc.edit(&snapshot)
+			if err := Save(path, snapshot); err != nil {
+				t.Fatalf("%s: expected no error saving, got %v", tc.name, err)
+			}
+		} else if err := os.WriteFile(path, []byte(tc.content), 0o644); err != nil {
+			t.Fatalf("%s: expected no error writing, got %v", tc.name, err)
+		}
+
+		_, err := Load(path)
+		if err == nil || !strings.HasPrefix(err.Error(), string(model.INVALID_STATE)) {
+			t.Errorf("%s: expected %s, got %v", tc.name, model.INVALID_STATE, err)
+		}
+	}
+}